@@ -0,0 +1,113 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"github.com/fairwindsops/goldilocks/pkg/manifests"
+	"github.com/fairwindsops/goldilocks/pkg/patch"
+	"github.com/fairwindsops/goldilocks/pkg/summary"
+	"github.com/fairwindsops/goldilocks/pkg/utils"
+)
+
+var manifestsDir string
+var summaryFile string
+
+func init() {
+	rootCmd.AddCommand(applyManifestsCmd)
+	applyManifestsCmd.PersistentFlags().StringVar(&manifestsDir, "dir", "", "Directory of YAML manifests to rewrite.")
+	applyManifestsCmd.PersistentFlags().StringVar(&summaryFile, "summary-file", "", "Read the recommendations from a file written by `goldilocks summary -f` instead of the cluster.")
+	applyManifestsCmd.PersistentFlags().StringVarP(&excludeContainers, "exclude-containers", "e", "", "Comma delimited list of containers to exclude from recommendations.")
+	applyManifestsCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "", "Limit the recommendations to only a single Namespace.")
+	applyManifestsCmd.PersistentFlags().StringVar(&tolerance, "tolerance", "", "Comma delimited list of per-resource tolerances. Containers whose requests are within the tolerance of the recommendation are skipped, e.g. cpu=10%,memory=10%.")
+	addRoundingFlags(applyManifestsCmd.PersistentFlags())
+	addHeadroomFlag(applyManifestsCmd.PersistentFlags())
+	addPatchLimitStrategyFlag(applyManifestsCmd.PersistentFlags())
+	applyManifestsCmd.PersistentFlags().BoolVar(&dryrun, "dry-run", false, "Don't actually rewrite the manifests, just list the changes.")
+	_ = applyManifestsCmd.MarkPersistentFlagRequired("dir")
+}
+
+var applyManifestsCmd = &cobra.Command{
+	Use:   "apply-manifests",
+	Short: "Apply vpa recommendations to local manifest files.",
+	Long: `Rewrite the container resources of the Deployment, StatefulSet, DaemonSet and other workload documents in a directory of YAML manifests.
+Documents are matched to recommendations by kind, namespace and name. Comments and formatting are preserved.`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var data summary.Summary
+		if summaryFile != "" {
+			// these shape the summary, which the summary file already is
+			for _, name := range []string{"namespace", "exclude-containers", "tolerance", "recommendation-headroom"} {
+				if cmd.Flags().Changed(name) {
+					klog.Fatalf("--%s can't be used with --summary-file, pass it to goldilocks summary when writing the file instead", name)
+				}
+			}
+			data = readSummaryFile(summaryFile)
+		} else {
+			var opts []summary.Option
+
+			// limit to a single namespace
+			if namespace != "" {
+				opts = append(opts, summary.ForNamespace(namespace))
+			}
+
+			// exclude containers from the summary
+			if excludeContainers != "" {
+				opts = append(opts, summary.ExcludeContainers(sets.New[string](strings.Split(excludeContainers, ",")...)))
+			}
+
+			// skip containers that are close enough to the recommendation
+			parsedTolerance, err := utils.ParseTolerance(tolerance)
+			if err != nil {
+				klog.Fatalf("Error parsing tolerance: %v", err)
+			}
+			opts = append(opts, summary.WithTolerance(parsedTolerance), summary.WithRoundingPolicy(getRoundingPolicy()), summary.WithHeadroom(getHeadroom()), summary.WithLimitStrategy(getPatchLimitStrategy()))
+
+			data, err = summary.NewSummarizer(opts...).GetSummary()
+			if err != nil {
+				klog.Fatalf("Error getting summary: %v", err)
+			}
+		}
+
+		patches := patch.FromSummary(data, getPatchLimitStrategy())
+		if summaryFile != "" {
+			patches = patch.Round(patches, getRoundingPolicy())
+		}
+
+		changes, err := manifests.Apply(manifestsDir, patches, dryrun)
+		if err != nil {
+			klog.Fatalf("Error applying recommendations to manifests: %v", err)
+		}
+
+		if len(changes) == 0 {
+			fmt.Println("No changes to the manifests in", manifestsDir)
+			return
+		}
+		for _, change := range changes {
+			fmt.Println(change.String())
+		}
+		if dryrun {
+			fmt.Printf("%d changes would be made to the manifests in %s\n", len(changes), manifestsDir)
+		} else {
+			fmt.Printf("%d changes were made to the manifests in %s\n", len(changes), manifestsDir)
+		}
+	},
+}
@@ -15,34 +15,84 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 
 	"github.com/fairwindsops/goldilocks/pkg/dashboard"
+	"github.com/fairwindsops/goldilocks/pkg/history"
+	"github.com/fairwindsops/goldilocks/pkg/kube"
+	"github.com/fairwindsops/goldilocks/pkg/summary"
+	"github.com/fairwindsops/goldilocks/pkg/utils"
 )
 
 var (
 	serverPort   int
+	bindAddress  string
+	tlsCertFile  string
+	tlsKeyFile   string
+	timeouts     = dashboard.DefaultTimeouts
 	showAllVPAs  bool
 	basePath     string
 	insightsHost string
 	enableCost   bool
+
+	historyFile      string
+	historyInterval  time.Duration
+	historyRetention time.Duration
+
+	authMode         string
+	authTokenHeader  string
+	authUserHeader   string
+	authGroupsHeader string
+	authProxyCA      string
+	authProxyNames   string
+	enableApply      bool
+
+	cacheStaleness time.Duration
 )
 
 func init() {
 	rootCmd.AddCommand(dashboardCmd)
 	dashboardCmd.PersistentFlags().IntVarP(&serverPort, "port", "p", 8080, "The port to serve the dashboard on.")
+	dashboardCmd.PersistentFlags().StringVar(&bindAddress, "bind-address", "", "The address to serve the dashboard on. All addresses if not set.")
+	dashboardCmd.PersistentFlags().StringVar(&tlsCertFile, "tls-cert-file", "", "File with the TLS certificate to serve the dashboard with. The certificate is reloaded when the file changes.")
+	dashboardCmd.PersistentFlags().StringVar(&tlsKeyFile, "tls-key-file", "", "File with the key of the TLS certificate.")
+	dashboardCmd.PersistentFlags().DurationVar(&timeouts.ReadHeader, "read-header-timeout", timeouts.ReadHeader, "How long to wait for the headers of a request.")
+	dashboardCmd.PersistentFlags().DurationVar(&timeouts.Read, "read-timeout", timeouts.Read, "How long to wait for a whole request.")
+	dashboardCmd.PersistentFlags().DurationVar(&timeouts.Write, "write-timeout", timeouts.Write, "How long writing a response may take.")
+	dashboardCmd.PersistentFlags().DurationVar(&timeouts.Idle, "idle-timeout", timeouts.Idle, "How long to keep idle connections open.")
+	dashboardCmd.PersistentFlags().DurationVar(&timeouts.Shutdown, "shutdown-timeout", timeouts.Shutdown, "How long to wait for requests in flight when shutting down.")
 	dashboardCmd.PersistentFlags().StringVarP(&excludeContainers, "exclude-containers", "e", "", "Comma delimited list of containers to exclude from recommendations.")
 	dashboardCmd.PersistentFlags().BoolVar(&onByDefault, "on-by-default", false, "Display every namespace that isn't explicitly excluded.")
 	dashboardCmd.PersistentFlags().BoolVar(&showAllVPAs, "show-all", false, "Display every VPA, even if it isn't managed by Goldilocks")
 	dashboardCmd.PersistentFlags().StringVar(&basePath, "base-path", "/", "Path on which the dashboard is served.")
 	dashboardCmd.PersistentFlags().BoolVar(&enableCost, "enable-cost", true, "If set to false, the cost integration will be disabled on the dashboard.")
 	dashboardCmd.PersistentFlags().StringVar(&insightsHost, "insights-host", "https://insights.fairwinds.com", "Insights host for retrieving optional cost data.")
+	dashboardCmd.PersistentFlags().StringVar(&tolerance, "tolerance", "", "Comma delimited list of per-resource tolerances within which current values count as equal to the recommendation, e.g. cpu=10%,memory=10%,cpu=5m,memory=16Mi.")
+	addRoundingFlags(dashboardCmd.PersistentFlags())
+	addHeadroomFlag(dashboardCmd.PersistentFlags())
+	addLimitStrategyFlag(dashboardCmd.PersistentFlags())
+	addPricingCatalogFlag(dashboardCmd.PersistentFlags())
+	dashboardCmd.PersistentFlags().StringVar(&authMode, "auth", string(dashboard.AuthNone), fmt.Sprintf("How users of the dashboard are authenticated. One of %v. Authenticated users only see the namespaces and workloads they can get.", dashboard.AuthModes))
+	dashboardCmd.PersistentFlags().StringVar(&authTokenHeader, "auth-token-header", "", "Header with the token to review in the token auth mode, e.g. X-Forwarded-Access-Token. The bearer token of the Authorization header is used if not set.")
+	dashboardCmd.PersistentFlags().StringVar(&authUserHeader, "auth-user-header", "X-Forwarded-User", "Header with the user set by the authenticating proxy in the proxy auth mode.")
+	dashboardCmd.PersistentFlags().StringVar(&authGroupsHeader, "auth-groups-header", "X-Forwarded-Groups", "Header with the comma delimited groups set by the authenticating proxy in the proxy auth mode.")
+	dashboardCmd.PersistentFlags().StringVar(&authProxyCA, "auth-proxy-client-ca", "", "File with the CA certificates that verify the client certificate of the authenticating proxy. Required in the proxy auth mode, which also needs --tls-cert-file. Headers of other clients are ignored.")
+	dashboardCmd.PersistentFlags().StringVar(&authProxyNames, "auth-proxy-allowed-names", "", "Comma delimited list of the common names of the client certificates of the authenticating proxy. Any name of the proxy client CA if not set.")
+	dashboardCmd.PersistentFlags().BoolVar(&enableApply, "enable-apply", false, "Let authenticated users apply recommendations to the workloads they can patch. Needs an auth mode, and the dashboard must be allowed to impersonate users.")
+	dashboardCmd.PersistentFlags().DurationVar(&cacheStaleness, "cache-staleness", 30*time.Second, "How far behind the cluster the cached VPAs and workloads of the dashboard may be. Set to 0 to list them for every request instead.")
+	dashboardCmd.PersistentFlags().StringVar(&historyFile, "history-file", "", "BoltDB file to store recommendation history snapshots in. History is disabled if not set.")
+	dashboardCmd.PersistentFlags().DurationVar(&historyInterval, "history-interval", time.Hour, "How often to record a snapshot of the summary into the history.")
+	dashboardCmd.PersistentFlags().DurationVar(&historyRetention, "history-retention", 30*24*time.Hour, "How long to keep history snapshots. Set to 0 to keep them forever.")
 }
 
 var dashboardCmd = &cobra.Command{
@@ -50,19 +100,131 @@ var dashboardCmd = &cobra.Command{
 	Short: "Run the goldilocks dashboard that will show recommendations.",
 	Long:  `Run the goldilocks dashboard that will show recommendations.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		// stop serving gracefully on SIGTERM, e.g. when the pod is deleted
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+		defer stop()
+
 		var validBasePath = validateBasePath(basePath)
-		router := dashboard.GetRouter(
+		excludedContainers := sets.New[string](strings.Split(excludeContainers, ",")...)
+		parsedTolerance, err := utils.ParseTolerance(tolerance)
+		if err != nil {
+			klog.Fatalf("Error parsing tolerance: %v", err)
+		}
+		rounding := getRoundingPolicy()
+		parsedHeadroom := getHeadroom()
+		limitStrategy := getLimitStrategy(limits)
+		costModel := getCostModel()
+		dashboardOpts := []dashboard.Option{
 			dashboard.OnPort(serverPort),
+			dashboard.OnAddress(bindAddress),
+			dashboard.WithTLS(tlsCertFile, tlsKeyFile),
+			dashboard.WithTimeouts(timeouts),
 			dashboard.BasePath(validBasePath),
-			dashboard.ExcludeContainers(sets.New[string](strings.Split(excludeContainers, ",")...)),
+			dashboard.ExcludeContainers(excludedContainers),
 			dashboard.OnByDefault(onByDefault),
 			dashboard.ShowAllVPAs(showAllVPAs),
 			dashboard.InsightsHost(insightsHost),
 			dashboard.EnableCost(enableCost),
-		)
-		http.Handle("/", router)
+			dashboard.WithTolerance(parsedTolerance),
+			dashboard.WithRoundingPolicy(rounding),
+			dashboard.WithHeadroom(parsedHeadroom),
+			dashboard.WithLimitStrategy(limitStrategy),
+			dashboard.WithCostModel(costModel),
+			dashboard.WithCostPeriod(getCostPeriod()),
+		}
+
+		parsedAuthMode, err := dashboard.ParseAuthMode(authMode)
+		if err != nil {
+			klog.Fatalf("Error parsing auth mode: %v", err)
+		}
+		if parsedAuthMode != dashboard.AuthNone {
+			auth := dashboard.NewAuth(kube.GetInstance(), parsedAuthMode)
+			auth.TokenHeader = authTokenHeader
+			auth.UserHeader = authUserHeader
+			auth.GroupsHeader = authGroupsHeader
+			if parsedAuthMode == dashboard.AuthProxy {
+				// anyone could claim to be any user if the headers were trusted from every client
+				if authProxyCA == "" || tlsCertFile == "" {
+					klog.Fatalf("the proxy auth mode needs --auth-proxy-client-ca and --tls-cert-file to verify the proxy")
+				}
+				auth.ProxyClientCA, err = dashboard.LoadCertPool(authProxyCA)
+				if err != nil {
+					klog.Fatalf("Error loading the proxy client CA: %v", err)
+				}
+				if authProxyNames != "" {
+					auth.ProxyAllowedNames = strings.Split(authProxyNames, ",")
+				}
+			}
+			dashboardOpts = append(dashboardOpts, dashboard.WithAuth(auth))
+			klog.Infof("Authenticating dashboard users with the %s auth mode", parsedAuthMode)
+		}
+		if enableApply {
+			if parsedAuthMode == dashboard.AuthNone {
+				klog.Fatalf("--enable-apply needs an --auth mode, recommendations are applied as the authenticated user")
+			}
+			// users are impersonated, so their headers must come from the proxy
+			if parsedAuthMode == dashboard.AuthProxy && authProxyCA == "" {
+				klog.Fatalf("--enable-apply in the proxy auth mode needs --auth-proxy-client-ca to verify the proxy")
+			}
+			dashboardOpts = append(dashboardOpts, dashboard.EnableApply(true))
+			klog.Info("Users can apply recommendations from the dashboard")
+		}
+
+		var summaryCache *summary.Cache
+		if cacheStaleness > 0 {
+			summaryCache = summary.NewCache(cacheStaleness)
+			dashboardOpts = append(dashboardOpts, dashboard.WithCache(summaryCache))
+			go func() {
+				if err := summaryCache.Run(ctx); err != nil {
+					klog.Errorf("Error running summary cache: %v", err)
+				}
+			}()
+		}
+
+		if historyFile != "" {
+			store, err := history.Open(historyFile, historyRetention)
+			if err != nil {
+				klog.Fatalf("Error opening history file %s: %v", historyFile, err)
+			}
+			defer func() {
+				if err := store.Close(); err != nil {
+					klog.Errorf("Error closing history file %s: %v", historyFile, err)
+				}
+			}()
+			dashboardOpts = append(dashboardOpts, dashboard.WithHistory(store))
+
+			recorder := history.Recorder{
+				Store:    store,
+				Interval: historyInterval,
+				Summarize: func() (summary.Summary, error) {
+					vpaLabels := utils.VPALabels
+					if showAllVPAs {
+						vpaLabels = map[string]string{}
+					}
+					return summary.NewSummarizer(
+						summary.ForVPAsWithLabels(vpaLabels),
+						summary.ExcludeContainers(excludedContainers),
+						summary.WithTolerance(parsedTolerance),
+						summary.WithRoundingPolicy(rounding),
+						summary.WithHeadroom(parsedHeadroom),
+						summary.WithLimitStrategy(limitStrategy),
+						summary.WithCostModel(costModel),
+						summary.FromCache(summaryCache),
+					).GetSummary()
+				},
+			}
+			go func() {
+				if err := recorder.Run(ctx); err != nil {
+					klog.Errorf("Error running history recorder: %v", err)
+				}
+			}()
+			klog.Infof("Recording recommendation history to %s every %s", historyFile, historyInterval)
+		}
+
 		klog.Infof("Starting goldilocks dashboard server on port %d and basePath %v", serverPort, validBasePath)
-		klog.Fatalf("%v", http.ListenAndServe(fmt.Sprintf(":%d", serverPort), nil))
+		if err := dashboard.ListenAndServe(ctx, dashboardOpts...); err != nil {
+			klog.Fatalf("%v", err)
+		}
 	},
 }
 
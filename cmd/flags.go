@@ -0,0 +1,128 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+
+	"github.com/fairwindsops/goldilocks/pkg/summary"
+	"github.com/fairwindsops/goldilocks/pkg/utils"
+)
+
+var (
+	roundCPU    string
+	roundMemory string
+	roundMode   string
+	minCPU      string
+	minMemory   string
+	headroom    string
+	limits      string
+
+	pricingCatalog string
+	costPeriod     string
+)
+
+// addRoundingFlags adds the flags for the recommendation rounding policy to a command
+func addRoundingFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&roundCPU, "round-cpu", "", "Round cpu recommendations to a multiple of this step, e.g. 10m.")
+	flags.StringVar(&roundMemory, "round-memory", "", "Round memory recommendations to a multiple of this step, e.g. 16Mi.")
+	flags.StringVar(&roundMode, "round-mode", string(utils.RoundUp), "How recommendations are rounded to a step. One of up or nearest.")
+	flags.StringVar(&minCPU, "min-cpu", "", "Minimum cpu recommendation after rounding, e.g. 10m.")
+	flags.StringVar(&minMemory, "min-memory", "", "Minimum memory recommendation after rounding, e.g. 32Mi.")
+}
+
+// getRoundingPolicy returns the rounding policy from the rounding flags
+func getRoundingPolicy() utils.RoundingPolicy {
+	policy, err := utils.NewRoundingPolicy(roundCPU, roundMemory, roundMode, minCPU, minMemory)
+	if err != nil {
+		klog.Fatalf("Error parsing rounding policy: %v", err)
+	}
+	return policy
+}
+
+// addHeadroomFlag adds the flag for the default recommendation headroom to a command
+func addHeadroomFlag(flags *pflag.FlagSet) {
+	flags.StringVar(&headroom, "recommendation-headroom", "", "Safety margin added on top of the recommended target for workloads and namespaces without the "+utils.RecommendationHeadroomAnnotation+" annotation, e.g. cpu=20%,memory=10%.")
+}
+
+// getHeadroom returns the headroom from the headroom flag
+func getHeadroom() utils.Headroom {
+	parsed, err := utils.ParseHeadroom(headroom)
+	if err != nil {
+		klog.Fatalf("Error parsing recommendation headroom: %v", err)
+	}
+	return parsed
+}
+
+// addLimitStrategyFlag adds the flag for the default limit strategy to a command
+func addLimitStrategyFlag(flags *pflag.FlagSet) {
+	flags.StringVar(&limits, "limit-strategy", "", fmt.Sprintf("How recommended requests and limits are derived from the recommendation in namespaces without the %s annotation. One of %v, no-cpu-limit can be combined with a ratio.", utils.LimitStrategyAnnotation, utils.LimitStrategies))
+}
+
+// addPatchLimitStrategyFlag adds the limit strategy flag to a command that writes patches, with --strategy as a deprecated alias
+func addPatchLimitStrategyFlag(flags *pflag.FlagSet) {
+	addLimitStrategyFlag(flags)
+	flags.StringVar(&limits, "strategy", "", "Deprecated alias of --limit-strategy.")
+	_ = flags.MarkDeprecated("strategy", "use --limit-strategy instead")
+}
+
+// getPatchLimitStrategy returns the limit strategy of commands that write patches, which need
+// requests and limits for every container and use the guaranteed strategy when none is set
+func getPatchLimitStrategy() utils.LimitStrategy {
+	if strings.TrimSpace(limits) == "" {
+		return getLimitStrategy(utils.LimitStrategyGuaranteed)
+	}
+	return getLimitStrategy(limits)
+}
+
+// getLimitStrategy parses the limit strategy of a flag
+func getLimitStrategy(value string) utils.LimitStrategy {
+	strategy, err := utils.ParseLimitStrategy(value)
+	if err != nil {
+		klog.Fatalf("Error parsing limit strategy: %v", err)
+	}
+	return strategy
+}
+
+// addPricingCatalogFlag adds the flags for the offline pricing catalog and the cost period to a command
+func addPricingCatalogFlag(flags *pflag.FlagSet) {
+	flags.StringVar(&pricingCatalog, "pricing-catalog", "", "YAML or JSON file with the cpu and memory prices used to calculate the cost of containers. Costs are not calculated if not set.")
+	flags.StringVar(&costPeriod, "cost-period", string(summary.CostPerHour), fmt.Sprintf("Period costs are reported for. One of %v.", summary.CostPeriods))
+}
+
+// getCostPeriod parses the cost period flag
+func getCostPeriod() summary.CostPeriod {
+	period, err := summary.ParseCostPeriod(costPeriod)
+	if err != nil {
+		klog.Fatalf("Error parsing cost period: %v", err)
+	}
+	return period
+}
+
+// getCostModel returns the cost model of the pricing catalog flag, or nil without a catalog
+func getCostModel() *summary.CostModel {
+	if pricingCatalog == "" {
+		return nil
+	}
+	catalog, err := summary.LoadPricingCatalog(pricingCatalog)
+	if err != nil {
+		klog.Fatalf("Error loading pricing catalog: %v", err)
+	}
+	return summary.NewCostModel(catalog, getCostPeriod())
+}
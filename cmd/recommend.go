@@ -0,0 +1,98 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"github.com/fairwindsops/goldilocks/pkg/patch"
+	"github.com/fairwindsops/goldilocks/pkg/summary"
+	"github.com/fairwindsops/goldilocks/pkg/utils"
+)
+
+var patchFormat string
+
+func init() {
+	rootCmd.AddCommand(recommendCmd)
+	recommendCmd.PersistentFlags().StringVarP(&excludeContainers, "exclude-containers", "e", "", "Comma delimited list of containers to exclude from recommendations.")
+	recommendCmd.PersistentFlags().StringVarP(&outputFile, "output-file", "f", "", "File to write the patches to.")
+	recommendCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "", "Limit the recommendations to only a single Namespace.")
+	recommendCmd.PersistentFlags().StringVar(&tolerance, "tolerance", "", "Comma delimited list of per-resource tolerances. Containers whose requests are within the tolerance of the recommendation are skipped, e.g. cpu=10%,memory=10%.")
+	addRoundingFlags(recommendCmd.PersistentFlags())
+	addHeadroomFlag(recommendCmd.PersistentFlags())
+	recommendCmd.PersistentFlags().StringVar(&patchFormat, "format", string(patch.FormatStrategicMerge), fmt.Sprintf("Format of the generated patches. One of %v.", patch.Formats))
+	addPatchLimitStrategyFlag(recommendCmd.PersistentFlags())
+}
+
+var recommendCmd = &cobra.Command{
+	Use:   "recommend",
+	Short: "Generate patches that apply the vpa recommendations.",
+	Long: `Gather all the vpa data and generate a patch for every workload that applies the recommendations.
+Patches can be written as strategic merge patches, kustomize patch files or kubectl set resources commands.
+The guaranteed strategy uses the target for requests and limits, the burstable strategy uses the lower bound for requests and the upper bound for limits.`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var opts []summary.Option
+
+		// limit to a single namespace
+		if namespace != "" {
+			opts = append(opts, summary.ForNamespace(namespace))
+		}
+
+		// exclude containers from the summary
+		if excludeContainers != "" {
+			opts = append(opts, summary.ExcludeContainers(sets.New[string](strings.Split(excludeContainers, ",")...)))
+		}
+
+		// skip containers that are close enough to the recommendation
+		parsedTolerance, err := utils.ParseTolerance(tolerance)
+		if err != nil {
+			klog.Fatalf("Error parsing tolerance: %v", err)
+		}
+		opts = append(opts, summary.WithTolerance(parsedTolerance), summary.WithRoundingPolicy(getRoundingPolicy()), summary.WithHeadroom(getHeadroom()), summary.WithLimitStrategy(getPatchLimitStrategy()))
+
+		summarizer := summary.NewSummarizer(opts...)
+		data, err := summarizer.GetSummary()
+		if err != nil {
+			klog.Fatalf("Error getting summary: %v", err)
+		}
+
+		patches := patch.FromSummary(data, getPatchLimitStrategy())
+
+		var buf bytes.Buffer
+		if err := patch.Write(&buf, patches, patch.Format(patchFormat)); err != nil {
+			klog.Fatalf("Error writing patches: %v", err)
+		}
+
+		if outputFile != "" {
+			err := os.WriteFile(outputFile, buf.Bytes(), 0644)
+			if err != nil {
+				klog.Fatalf("Failed to write patches to file: %v", err)
+			}
+
+			fmt.Println("Patches have been written to", outputFile)
+
+		} else {
+			fmt.Print(buf.String())
+		}
+	},
+}
@@ -15,27 +15,49 @@
 package cmd
 
 import (
-	"encoding/json"
+	"bytes"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 
 	"github.com/fairwindsops/goldilocks/pkg/summary"
+	"github.com/fairwindsops/goldilocks/pkg/utils"
 )
 
 var excludeContainers string
 var outputFile string
 var namespace string
+var tolerance string
+var outputFormat string
+var sortBy string
+var workloadSelector string
+var namespaceSelector string
+var kinds string
+var minDifference float64
+var top int
 
 func init() {
 	rootCmd.AddCommand(summaryCmd)
 	summaryCmd.PersistentFlags().StringVarP(&excludeContainers, "exclude-containers", "e", "", "Comma delimited list of containers to exclude from recommendations.")
 	summaryCmd.PersistentFlags().StringVarP(&outputFile, "output-file", "f", "", "File to write output from audit.")
 	summaryCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "", "Limit the summary to only a single Namespace.")
+	summaryCmd.PersistentFlags().StringVar(&tolerance, "tolerance", "", "Comma delimited list of per-resource tolerances within which current requests count as equal to the recommendation, e.g. cpu=10%,memory=10%,cpu=5m,memory=16Mi.")
+	addRoundingFlags(summaryCmd.PersistentFlags())
+	addHeadroomFlag(summaryCmd.PersistentFlags())
+	addLimitStrategyFlag(summaryCmd.PersistentFlags())
+	addPricingCatalogFlag(summaryCmd.PersistentFlags())
+	summaryCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", string(summary.OutputJSON), fmt.Sprintf("Output format. One of %v.", summary.OutputFormats))
+	summaryCmd.PersistentFlags().StringVar(&sortBy, "sort-by", string(summary.SortByName), fmt.Sprintf("Order of the rows of the table, wide, csv and markdown output. One of %v.", summary.SortOrders))
+	summaryCmd.PersistentFlags().StringVarP(&workloadSelector, "selector", "l", "", "Label selector to limit the summary to matching workloads, e.g. app=web.")
+	summaryCmd.PersistentFlags().StringVar(&namespaceSelector, "namespace-selector", "", "Label selector to limit the summary to workloads in matching Namespaces, e.g. team=payments.")
+	summaryCmd.PersistentFlags().StringVar(&kinds, "kind", "", "Comma delimited list of controller kinds to limit the summary to, e.g. Deployment,StatefulSet.")
+	summaryCmd.PersistentFlags().Float64Var(&minDifference, "min-diff", 0, "Only include containers whose cpu or memory request differs from the recommendation by more than this percentage.")
+	summaryCmd.PersistentFlags().IntVar(&top, "top", 0, "Only include the first N containers in the --sort-by order, e.g. --top 20 --sort-by cpu-over for the 20 containers with the most unused cpu.")
 }
 
 var summaryCmd = &cobra.Command{
@@ -57,19 +79,57 @@ By default the summary will be about all VPAs in all namespaces.`,
 			opts = append(opts, summary.ExcludeContainers(sets.New[string](strings.Split(excludeContainers, ",")...)))
 		}
 
+		// treat small differences from the recommendation as equal
+		parsedTolerance, err := utils.ParseTolerance(tolerance)
+		if err != nil {
+			klog.Fatalf("Error parsing tolerance: %v", err)
+		}
+		opts = append(opts, summary.WithTolerance(parsedTolerance), summary.WithRoundingPolicy(getRoundingPolicy()), summary.WithHeadroom(getHeadroom()), summary.WithLimitStrategy(getLimitStrategy(limits)))
+
+		// calculate the cost of the containers
+		if costModel := getCostModel(); costModel != nil {
+			opts = append(opts, summary.WithCostModel(costModel))
+		}
+
+		// limit the summary to matching workloads
+		if workloadSelector != "" {
+			selector, err := labels.Parse(workloadSelector)
+			if err != nil {
+				klog.Fatalf("Error parsing selector: %v", err)
+			}
+			opts = append(opts, summary.ForWorkloadsMatching(selector))
+		}
+		if namespaceSelector != "" {
+			selector, err := labels.Parse(namespaceSelector)
+			if err != nil {
+				klog.Fatalf("Error parsing namespace selector: %v", err)
+			}
+			opts = append(opts, summary.ForNamespacesMatching(selector))
+		}
+		if kinds != "" {
+			opts = append(opts, summary.ForKinds(sets.New[string](strings.Split(kinds, ",")...)))
+		}
+		if minDifference > 0 {
+			opts = append(opts, summary.WithMinimumDifference(minDifference))
+		}
+
 		summarizer := summary.NewSummarizer(opts...)
 		data, err := summarizer.GetSummary()
 		if err != nil {
 			klog.Fatalf("Error getting summary: %v", err)
 		}
 
-		summaryJSON, err := json.Marshal(data)
-		if err != nil {
-			klog.Fatalf("Error marshalling JSON: %v", err)
+		if top > 0 {
+			data = summary.Top(data, summary.SortBy(sortBy), top)
+		}
+
+		var buf bytes.Buffer
+		if err := summary.Write(&buf, data, summary.OutputFormat(outputFormat), summary.SortBy(sortBy)); err != nil {
+			klog.Fatalf("Error writing summary: %v", err)
 		}
 
 		if outputFile != "" {
-			err := os.WriteFile(outputFile, summaryJSON, 0644)
+			err := os.WriteFile(outputFile, buf.Bytes(), 0644)
 			if err != nil {
 				klog.Fatalf("Failed to write summary to file: %v", err)
 			}
@@ -77,7 +137,7 @@ By default the summary will be about all VPAs in all namespaces.`,
 			fmt.Println("Summary has been written to", outputFile)
 
 		} else {
-			fmt.Println(string(summaryJSON))
+			fmt.Print(buf.String())
 		}
 	},
 }
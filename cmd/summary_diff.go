@@ -0,0 +1,83 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"github.com/fairwindsops/goldilocks/pkg/summary"
+	"github.com/fairwindsops/goldilocks/pkg/utils"
+)
+
+var diffFormat string
+var diffThreshold float64
+
+func init() {
+	summaryCmd.AddCommand(summaryDiffCmd)
+	summaryDiffCmd.Flags().StringVarP(&diffFormat, "output", "o", string(summary.DiffText), fmt.Sprintf("Output format. One of %v.", summary.DiffFormats))
+	summaryDiffCmd.Flags().Float64Var(&diffThreshold, "threshold", 10, "Only report recommendations that changed by more than this percentage.")
+}
+
+var summaryDiffCmd = &cobra.Command{
+	Use:   "diff <old> <new>",
+	Short: "Compare two summaries.",
+	Long: `Compare two JSON or YAML files written by goldilocks summary -f.
+Reports workloads and containers that were added or removed, recommendations that changed by more than the threshold, and changes of the current requests and limits.
+Exits with 1 when there are differences, except for changes of the current requests and limits within the --tolerance.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		// the other flags of goldilocks summary shape a summary, which the files already are
+		summaryCmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+			if flag.Name != "tolerance" && cmd.LocalNonPersistentFlags().Lookup(flag.Name) == nil && flag.Changed {
+				klog.Fatalf("--%s can't be used with goldilocks summary diff, pass it to goldilocks summary when writing the files instead", flag.Name)
+			}
+		})
+
+		old := readSummaryFile(args[0])
+		new := readSummaryFile(args[1])
+
+		parsedTolerance, err := utils.ParseTolerance(tolerance)
+		if err != nil {
+			klog.Fatalf("Error parsing tolerance: %v", err)
+		}
+
+		differences := summary.Diff(old, new, diffThreshold, parsedTolerance)
+		if err := summary.WriteDiff(os.Stdout, differences, summary.DiffFormat(diffFormat)); err != nil {
+			klog.Fatalf("Error writing differences: %v", err)
+		}
+		if summary.OutsideTolerance(differences) {
+			exitCode = 1
+		}
+	},
+}
+
+// readSummaryFile reads a summary written by goldilocks summary -f, in JSON or YAML
+func readSummaryFile(path string) summary.Summary {
+	var data summary.Summary
+	content, err := os.ReadFile(path)
+	if err != nil {
+		klog.Fatalf("Error reading summary file: %v", err)
+	}
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		klog.Fatalf("Error parsing summary file %s: %v", path, err)
+	}
+	return data
+}
@@ -0,0 +1,315 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"github.com/fairwindsops/goldilocks/pkg/summary"
+)
+
+// apiQuery are the filters, pagination and field selection of a JSON API request
+type apiQuery struct {
+	// options limit the summary to the matching workloads
+	options []summary.Option
+	offset  int
+	limit   int
+	// fields are the fields of the workloads to reply with, e.g. controllerName or containers.target
+	fields []string
+}
+
+// apiParameter is a query parameter of the JSON API, described in the OpenAPI document
+type apiParameter struct {
+	name        string
+	schemaType  string
+	description string
+}
+
+var (
+	// costParameters are the prices of the cost settings of the dashboard
+	costParameters = []apiParameter{
+		{name: "costPerCPU", schemaType: "number", description: "Hourly price of a cpu core, used with costPerGB instead of the pricing catalog."},
+		{name: "costPerGB", schemaType: "number", description: "Hourly price of a GB of memory, used with costPerCPU instead of the pricing catalog."},
+	}
+	// filterParameters limit the summary to the matching workloads
+	filterParameters = []apiParameter{
+		{name: "selector", schemaType: "string", description: "Label selector of the workloads, e.g. app=web."},
+		{name: "namespaceSelector", schemaType: "string", description: "Label selector of the namespaces of the workloads, e.g. team=payments."},
+		{name: "kind", schemaType: "string", description: "Comma delimited list of workload kinds, e.g. Deployment,StatefulSet."},
+		{name: "minDiff", schemaType: "number", description: "Only containers whose cpu or memory request differs from the recommendation by more than this percentage."},
+	}
+	// pageParameters select a page of the results
+	pageParameters = []apiParameter{
+		{name: "offset", schemaType: "integer", description: "Number of results to skip."},
+		{name: "limit", schemaType: "integer", description: "Maximum number of results, all results if not set."},
+	}
+	// fieldsParameter selects the fields of the workloads
+	fieldsParameter = apiParameter{name: "fields", schemaType: "string", description: "Comma delimited list of workload fields to reply with, containers.<field> selects a field of every container."}
+)
+
+// parseAPIQuery parses the query parameters of a JSON API request
+func parseAPIQuery(query url.Values) (apiQuery, error) {
+	var q apiQuery
+	if value := query.Get("selector"); value != "" {
+		selector, err := labels.Parse(value)
+		if err != nil {
+			return q, fmt.Errorf("invalid selector: %v", err)
+		}
+		q.options = append(q.options, summary.ForWorkloadsMatching(selector))
+	}
+	if value := query.Get("namespaceSelector"); value != "" {
+		selector, err := labels.Parse(value)
+		if err != nil {
+			return q, fmt.Errorf("invalid namespaceSelector: %v", err)
+		}
+		q.options = append(q.options, summary.ForNamespacesMatching(selector))
+	}
+	if value := query.Get("kind"); value != "" {
+		q.options = append(q.options, summary.ForKinds(sets.New[string](strings.Split(value, ",")...)))
+	}
+	if value := query.Get("minDiff"); value != "" {
+		minDifference, err := strconv.ParseFloat(value, 64)
+		if err != nil || minDifference < 0 {
+			return q, fmt.Errorf("invalid minDiff %q, must be a percentage", value)
+		}
+		q.options = append(q.options, summary.WithMinimumDifference(minDifference))
+	}
+
+	var err error
+	if q.offset, err = parseCount(query, "offset"); err != nil {
+		return q, err
+	}
+	if q.limit, err = parseCount(query, "limit"); err != nil {
+		return q, err
+	}
+	if value := query.Get("fields"); value != "" {
+		q.fields = strings.Split(value, ",")
+	}
+	return q, nil
+}
+
+func parseCount(query url.Values, name string) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("invalid %s %q, must be a positive number", name, value)
+	}
+	return count, nil
+}
+
+// paginated returns true if the request asks for a page of the results
+func (q apiQuery) paginated() bool {
+	return q.offset > 0 || q.limit > 0
+}
+
+// setPageHeaders sets the total number of results, and a link to the next page if there is one
+func (q apiQuery) setPageHeaders(w http.ResponseWriter, r *http.Request, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if q.limit > 0 && q.offset+q.limit < total {
+		next := *r.URL
+		values := next.Query()
+		values.Set("offset", strconv.Itoa(q.offset+q.limit))
+		next.RawQuery = values.Encode()
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.String()))
+	}
+}
+
+// writeSummary replies with the page of the summary and the selected fields of its workloads
+func (q apiQuery) writeSummary(w http.ResponseWriter, r *http.Request, data summary.Summary) {
+	if q.paginated() {
+		var total int
+		data, total = summary.Page(data, q.offset, q.limit)
+		q.setPageHeaders(w, r, total)
+	}
+	if len(q.fields) == 0 {
+		writeJSON(w, data)
+		return
+	}
+
+	var selected map[string]any
+	if err := remarshal(data, &selected); err != nil {
+		klog.Errorf("Error selecting fields %v", err)
+		http.Error(w, "Error selecting fields", http.StatusInternalServerError)
+		return
+	}
+	namespaces, _ := selected["namespaces"].(map[string]any)
+	for _, ns := range namespaces {
+		workloads, _ := ns.(map[string]any)["workloads"].(map[string]any)
+		for key, workload := range workloads {
+			workloads[key] = selectFields(workload.(map[string]any), q.fields)
+		}
+	}
+	writeJSON(w, selected)
+}
+
+// selectFields keeps only the fields of a workload. containers.<field> keeps a field of every container.
+func selectFields(workload map[string]any, fields []string) map[string]any {
+	selected := map[string]any{}
+	for _, field := range fields {
+		name, containerField, nested := strings.Cut(field, ".")
+		value, ok := workload[name]
+		if !ok {
+			continue
+		}
+		if !nested || name != "containers" {
+			selected[name] = value
+			continue
+		}
+
+		containers, _ := value.(map[string]any)
+		selectedContainers, _ := selected[name].(map[string]any)
+		if selectedContainers == nil {
+			selectedContainers = map[string]any{}
+			selected[name] = selectedContainers
+		}
+		for containerName, container := range containers {
+			containerValue, ok := container.(map[string]any)[containerField]
+			if !ok {
+				continue
+			}
+			selectedContainer, _ := selectedContainers[containerName].(map[string]any)
+			if selectedContainer == nil {
+				selectedContainer = map[string]any{}
+				selectedContainers[containerName] = selectedContainer
+			}
+			selectedContainer[containerField] = containerValue
+		}
+	}
+	return selected
+}
+
+// NamespacesAPI replies with the JSON list of all goldilocks enabled namespaces
+func NamespacesAPI(opts Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseAPIQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		names, err := listNamespaces(opts, r)
+		if err != nil {
+			klog.Errorf("Error getting namespace list: %v", err)
+			http.Error(w, "Error getting namespace list", http.StatusInternalServerError)
+			return
+		}
+		sort.Strings(names)
+		if q.paginated() {
+			q.setPageHeaders(w, r, len(names))
+			names = names[min(q.offset, len(names)):]
+			if q.limit > 0 && q.limit < len(names) {
+				names = names[:q.limit]
+			}
+		}
+
+		type namespace struct {
+			Name string `json:"name"`
+		}
+		data := struct {
+			Namespaces []namespace `json:"namespaces"`
+		}{Namespaces: []namespace{}}
+		for _, name := range names {
+			data.Namespaces = append(data.Namespaces, namespace{Name: name})
+		}
+		writeJSON(w, data)
+	})
+}
+
+// WorkloadAPI replies with the JSON summary of a single workload
+func WorkloadAPI(opts Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		namespace, kind, name := vars["namespace"], vars["kind"], vars["name"]
+
+		q, err := parseAPIQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		vpaData, err := getVPAData(opts, r, namespace, r.URL.Query().Get("costPerCPU"), r.URL.Query().Get("costPerGB"), summary.ForKinds(sets.New(kind)))
+		if err != nil {
+			klog.Errorf("Error getting vpa data %v", err)
+			http.Error(w, "Error getting vpa data", http.StatusInternalServerError)
+			return
+		}
+
+		workload, ok := findWorkloadSummary(vpaData, namespace, kind, name)
+		if !ok {
+			http.Error(w, fmt.Sprintf("no summary for %s %s in namespace %s", kind, name, namespace), http.StatusNotFound)
+			return
+		}
+
+		if len(q.fields) == 0 {
+			writeJSON(w, workload)
+			return
+		}
+		var selected map[string]any
+		if err := remarshal(workload, &selected); err != nil {
+			klog.Errorf("Error selecting fields %v", err)
+			http.Error(w, "Error selecting fields", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, selectFields(selected, q.fields))
+	})
+}
+
+// remarshal converts a value to another type through its JSON
+func remarshal(value any, into any) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, into)
+}
+
+// findWorkloadSummary returns the summary of the workload of the kind with the name. Workloads of several
+// API versions can share the kind and name, the first key wins.
+func findWorkloadSummary(data summary.Summary, namespace, kind, name string) (summary.WorkloadSummary, bool) {
+	var keys []string
+	for key, workload := range data.Namespaces[namespace].Workloads {
+		if workload.ControllerType == kind && workload.ControllerName == name {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return summary.WorkloadSummary{}, false
+	}
+	sort.Strings(keys)
+	return data.Namespaces[namespace].Workloads[keys[0]], true
+}
+
+// writeJSON replies with the value as JSON
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		klog.Errorf("Error writing json %v", err)
+		http.Error(w, "Error writing json", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAPIQuery(t *testing.T) {
+	q, err := parseAPIQuery(url.Values{
+		"selector": {"app=web"},
+		"kind":     {"Deployment,StatefulSet"},
+		"minDiff":  {"10"},
+		"offset":   {"20"},
+		"limit":    {"10"},
+		"fields":   {"controllerName,containers.target"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, q.options, 3)
+	assert.Equal(t, 20, q.offset)
+	assert.Equal(t, 10, q.limit)
+	assert.Equal(t, []string{"controllerName", "containers.target"}, q.fields)
+	assert.True(t, q.paginated())
+
+	for name, query := range map[string]url.Values{
+		"selector":       {"selector": {"app in (web"}},
+		"minDiff":        {"minDiff": {"much"}},
+		"negative limit": {"limit": {"-1"}},
+		"offset":         {"offset": {"first"}},
+	} {
+		_, err := parseAPIQuery(query)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	workload := map[string]any{
+		"controllerName": "web",
+		"controllerType": "Deployment",
+		"containers": map[string]any{
+			"app":     map[string]any{"containerName": "app", "target": "100m", "requests": "200m"},
+			"sidecar": map[string]any{"containerName": "sidecar", "target": "10m"},
+		},
+	}
+
+	assert.Equal(t, map[string]any{
+		"controllerName": "web",
+		"containers": map[string]any{
+			"app":     map[string]any{"target": "100m", "requests": "200m"},
+			"sidecar": map[string]any{"target": "10m"},
+		},
+	}, selectFields(workload, []string{"controllerName", "containers.target", "containers.requests", "unknown"}))
+}
+
+func TestSetPageHeaders(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api?limit=10&kind=Deployment", nil)
+
+	w := httptest.NewRecorder()
+	apiQuery{limit: 10}.setPageHeaders(w, r, 25)
+	assert.Equal(t, "25", w.Header().Get("X-Total-Count"))
+	assert.Equal(t, `</api?kind=Deployment&limit=10&offset=10>; rel="next"`, w.Header().Get("Link"))
+
+	w = httptest.NewRecorder()
+	apiQuery{offset: 20, limit: 10}.setPageHeaders(w, r, 25)
+	assert.Empty(t, w.Header().Get("Link"))
+}
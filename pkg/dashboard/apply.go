@@ -0,0 +1,357 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+
+	"github.com/fairwindsops/goldilocks/pkg/kube"
+	"github.com/fairwindsops/goldilocks/pkg/patch"
+	"github.com/fairwindsops/goldilocks/pkg/summary"
+	"github.com/fairwindsops/goldilocks/pkg/utils"
+)
+
+const (
+	// applyFieldManager is the field manager of the resources patched from the dashboard
+	applyFieldManager = "goldilocks-dashboard"
+	// applyEventReason is the reason of the Events recorded for applied recommendations
+	applyEventReason = "RecommendationApplied"
+	// maxApplyRequestBytes is the size limit of the body of an apply request
+	maxApplyRequestBytes = 64 << 10
+	// maxEventMessageLength is the length Event messages are truncated to
+	maxEventMessageLength = 1024
+	// systemPrefix is the prefix of the users and groups of Kubernetes itself, e.g. system:masters
+	systemPrefix = "system:"
+)
+
+// applyStrategies are the recommendations that can be applied
+var applyStrategies = map[string]utils.LimitStrategy{
+	utils.LimitStrategyGuaranteed: {Ratio: 1},
+	utils.LimitStrategyBurstable:  {Burstable: true},
+}
+
+// applyRequest is the body of a request to apply a recommendation to a workload
+type applyRequest struct {
+	// Strategy is the recommendation to apply, guaranteed or burstable
+	Strategy string `json:"strategy"`
+	// Containers are the containers to patch, every container with a recommendation if empty
+	Containers []string `json:"containers,omitempty"`
+	// DryRun only returns the changes, without patching the workload
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// applyResponse are the changes of the resources of the containers of a workload
+type applyResponse struct {
+	Namespace string           `json:"namespace"`
+	Kind      string           `json:"kind"`
+	Name      string           `json:"name"`
+	Strategy  string           `json:"strategy"`
+	DryRun    bool             `json:"dryRun"`
+	Changes   []resourceChange `json:"changes"`
+}
+
+// resourceChange is a change of a request or limit of a container, from or to is empty when it is not set
+type resourceChange struct {
+	Container string `json:"container"`
+	// Resource is the changed request or limit, e.g. requests.cpu
+	Resource string `json:"resource"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+}
+
+func (c resourceChange) String() string {
+	from, to := c.From, c.To
+	if from == "" {
+		from = "unset"
+	}
+	if to == "" {
+		to = "unset"
+	}
+	return fmt.Sprintf("%s %s %s -> %s", c.Container, c.Resource, from, to)
+}
+
+// applier patches workloads as the users of the dashboard, and records an Event for every patch
+type applier struct {
+	restMapper meta.RESTMapper
+	// clientFor returns a client that acts as the user
+	clientFor func(user authenticationv1.UserInfo) (dynamic.Interface, error)
+	// events records the Events with the permissions of the dashboard
+	events kubernetes.Interface
+}
+
+// newApplier returns an applier that impersonates the users of the dashboard
+func newApplier() *applier {
+	return &applier{
+		restMapper: kube.GetDynamicInstance().RESTMapper,
+		clientFor: func(user authenticationv1.UserInfo) (dynamic.Interface, error) {
+			extra := map[string][]string{}
+			for key, value := range user.Extra {
+				extra[key] = value
+			}
+			return kube.GetImpersonatingDynamicClient(rest.ImpersonationConfig{
+				UserName: user.Username,
+				UID:      user.UID,
+				Groups:   user.Groups,
+				Extra:    extra,
+			})
+		},
+		events: kube.GetInstance().Client,
+	}
+}
+
+// impersonationAllowed returns an error for users and groups of Kubernetes itself from the headers of
+// the proxy, which the dashboard doesn't impersonate even if the proxy sets them
+func impersonationAllowed(opts Options, user authenticationv1.UserInfo) error {
+	if opts.Auth == nil || opts.Auth.Mode != AuthProxy {
+		return nil
+	}
+	if strings.HasPrefix(user.Username, systemPrefix) {
+		return fmt.Errorf("recommendations can't be applied as the %s user", user.Username)
+	}
+	for _, group := range user.Groups {
+		if strings.HasPrefix(group, systemPrefix) {
+			return fmt.Errorf("recommendations can't be applied with the %s group", group)
+		}
+	}
+	return nil
+}
+
+// Apply patches the resources of a workload to the guaranteed or burstable recommendation as the
+// user of the request, and replies with the changes. Dry runs only reply with the changes.
+func Apply(opts Options) http.Handler {
+	return applyRecommendation(opts, newApplier())
+}
+
+func applyRecommendation(opts Options, a *applier) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		namespace, kind, name := vars["namespace"], vars["kind"], vars["name"]
+
+		// a JSON body can't be sent cross-site without a CORS preflight
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+			http.Error(w, "the body must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+		user, ok := r.Context().Value(userContextKey{}).(authenticationv1.UserInfo)
+		if !ok || user.Username == "" {
+			http.Error(w, "applying recommendations needs an authenticated user", http.StatusForbidden)
+			return
+		}
+		if err := impersonationAllowed(opts, user); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		var request applyRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxApplyRequestBytes)).Decode(&request); err != nil {
+			http.Error(w, fmt.Sprintf("invalid body: %v", err), http.StatusBadRequest)
+			return
+		}
+		strategy, ok := applyStrategies[request.Strategy]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown strategy %q, must be one of %v", request.Strategy, sets.List(sets.KeySet(applyStrategies))), http.StatusBadRequest)
+			return
+		}
+
+		vpaData, err := getVPAData(opts, r, namespace, "", "", summary.ForKinds(sets.New(kind)))
+		if err != nil {
+			klog.Errorf("Error getting vpa data %v", err)
+			http.Error(w, "Error getting vpa data", http.StatusInternalServerError)
+			return
+		}
+		workload, ok := findWorkloadSummary(vpaData, namespace, kind, name)
+		if !ok {
+			http.Error(w, fmt.Sprintf("no summary for %s %s in namespace %s", kind, name, namespace), http.StatusNotFound)
+			return
+		}
+		p, err := patch.ForWorkload(namespace, workload, strategy, request.Containers...)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		changes, err := a.apply(r.Context(), user, workload.APIVersion, p, request)
+		if err != nil {
+			code := http.StatusInternalServerError
+			if status, ok := err.(apierrors.APIStatus); ok && status.Status().Code != 0 {
+				code = int(status.Status().Code)
+			}
+			klog.Errorf("Error applying the %s recommendation to %s %s/%s as %s: %v", request.Strategy, kind, namespace, name, user.Username, err)
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		writeJSON(w, applyResponse{
+			Namespace: namespace,
+			Kind:      kind,
+			Name:      name,
+			Strategy:  request.Strategy,
+			DryRun:    request.DryRun,
+			Changes:   changes,
+		})
+	})
+}
+
+// apply patches the workload as the user, only with a server-side dry run for dry run requests, and
+// returns the changes of the resources. The patch is authorized with the RBAC of the user.
+func (a *applier) apply(ctx context.Context, user authenticationv1.UserInfo, apiVersion string, p patch.Patch, request applyRequest) ([]resourceChange, error) {
+	gvk := schema.FromAPIVersionAndKind(apiVersion, p.Kind)
+	mapping, err := a.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+	client, err := a.clientFor(user)
+	if err != nil {
+		return nil, err
+	}
+	resource := client.Resource(mapping.Resource).Namespace(p.Namespace)
+
+	body, err := p.StrategicMerge()
+	if err != nil {
+		return nil, err
+	}
+	current, err := resource.Get(ctx, p.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	options := metav1.PatchOptions{FieldManager: applyFieldManager}
+	if request.DryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	patched, err := resource.Patch(ctx, p.Name, types.StrategicMergePatchType, body, options)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := resourceChanges(current, patched, p)
+	if request.DryRun {
+		return changes, nil
+	}
+
+	klog.Infof("%s applied the %s recommendation to %s %s/%s: %v", user.Username, request.Strategy, p.Kind, p.Namespace, p.Name, changes)
+	a.recordEvent(ctx, user, request.Strategy, patched, changes)
+	return changes, nil
+}
+
+// recordEvent records an Event on the workload for the audit trail of the applied recommendation
+func (a *applier) recordEvent(ctx context.Context, user authenticationv1.UserInfo, strategy string, workload *unstructured.Unstructured, changes []resourceChange) {
+	descriptions := make([]string, 0, len(changes))
+	for _, change := range changes {
+		descriptions = append(descriptions, change.String())
+	}
+	message := fmt.Sprintf("%s applied the %s recommendation of goldilocks", user.Username, strategy)
+	if len(descriptions) > 0 {
+		message += ": " + strings.Join(descriptions, ", ")
+	}
+	if len(message) > maxEventMessageLength {
+		message = message[:maxEventMessageLength-3] + "..."
+	}
+
+	now := metav1.NewTime(time.Now())
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: workload.GetName() + ".",
+			Namespace:    workload.GetNamespace(),
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion:      workload.GetAPIVersion(),
+			Kind:            workload.GetKind(),
+			Name:            workload.GetName(),
+			Namespace:       workload.GetNamespace(),
+			UID:             workload.GetUID(),
+			ResourceVersion: workload.GetResourceVersion(),
+		},
+		Reason:              applyEventReason,
+		Message:             message,
+		Type:                corev1.EventTypeNormal,
+		Source:              corev1.EventSource{Component: applyFieldManager},
+		ReportingController: applyFieldManager,
+		FirstTimestamp:      now,
+		LastTimestamp:       now,
+		Count:               1,
+	}
+	if _, err := a.events.CoreV1().Events(workload.GetNamespace()).Create(ctx, event, metav1.CreateOptions{}); err != nil {
+		klog.Errorf("Error recording the event of the applied recommendation of %s %s/%s: %v", workload.GetKind(), workload.GetNamespace(), workload.GetName(), err)
+	}
+}
+
+// resourceChanges returns the changed requests and limits of the patched containers, sorted by container and resource
+func resourceChanges(current, patched *unstructured.Unstructured, p patch.Patch) []resourceChange {
+	changes := []resourceChange{}
+	for _, c := range p.Containers {
+		before := containerResources(current, c.Name, c.Init)
+		after := containerResources(patched, c.Name, c.Init)
+		keys := sets.KeySet(before).Union(sets.KeySet(after))
+		for _, key := range sets.List(keys) {
+			if before[key] != after[key] {
+				changes = append(changes, resourceChange{Container: c.Name, Resource: key, From: before[key], To: after[key]})
+			}
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Container < changes[j].Container
+	})
+	return changes
+}
+
+// containerResources returns the requests and limits of the container of the workload by e.g. requests.cpu
+func containerResources(workload *unstructured.Unstructured, container string, init bool) map[string]string {
+	podSpec := []string{"spec", "template", "spec"}
+	if workload.GetKind() == "CronJob" {
+		podSpec = []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	}
+	field := "containers"
+	if init {
+		field = "initContainers"
+	}
+	containers, _, _ := unstructured.NestedSlice(workload.Object, append(podSpec, field)...)
+
+	resources := map[string]string{}
+	for _, item := range containers {
+		c, ok := item.(map[string]any)
+		if !ok || c["name"] != container {
+			continue
+		}
+		for _, kind := range []string{"requests", "limits"} {
+			values, _, _ := unstructured.NestedMap(c, "resources", kind)
+			for name, value := range values {
+				resources[kind+"."+name] = fmt.Sprint(value)
+			}
+		}
+	}
+	return resources
+}
@@ -0,0 +1,258 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+
+	"github.com/fairwindsops/goldilocks/pkg/kube"
+	"github.com/fairwindsops/goldilocks/pkg/patch"
+)
+
+func testDeployment(cpu string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]any{"name": "web", "namespace": "team-a", "uid": "1234", "resourceVersion": "1"},
+		"spec": map[string]any{"template": map[string]any{"spec": map[string]any{
+			"containers": []any{
+				map[string]any{"name": "app", "resources": map[string]any{
+					"requests": map[string]any{"cpu": cpu, "memory": "128Mi"},
+				}},
+				map[string]any{"name": "sidecar"},
+			},
+		}}},
+	}}
+}
+
+// patchOptionsClient records the options of patches, which the fake dynamic client ignores
+type patchOptionsClient struct {
+	dynamic.Interface
+	options *[]metav1.PatchOptions
+}
+
+func (c patchOptionsClient) Resource(resource schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return patchOptionsResource{NamespaceableResourceInterface: c.Interface.Resource(resource), options: c.options}
+}
+
+type patchOptionsResource struct {
+	dynamic.NamespaceableResourceInterface
+	options *[]metav1.PatchOptions
+}
+
+func (r patchOptionsResource) Namespace(namespace string) dynamic.ResourceInterface {
+	return patchOptionsNamespacedResource{ResourceInterface: r.NamespaceableResourceInterface.Namespace(namespace), options: r.options}
+}
+
+type patchOptionsNamespacedResource struct {
+	dynamic.ResourceInterface
+	options *[]metav1.PatchOptions
+}
+
+func (r patchOptionsNamespacedResource) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, options metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	*r.options = append(*r.options, options)
+	return r.ResourceInterface.Patch(ctx, name, pt, data, options, subresources...)
+}
+
+func testApplier(t *testing.T, patchOptions *[]metav1.PatchOptions) (*applier, *fake.Clientset, *authenticationv1.UserInfo) {
+	fakeClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), testDeployment("100m"))
+	// the tracker of the fake client doesn't apply strategic merge patches to unstructured objects
+	fakeClient.PrependReactor("patch", "deployments", func(action core.Action) (bool, runtime.Object, error) {
+		patchAction := action.(core.PatchAction)
+		assert.Equal(t, types.StrategicMergePatchType, patchAction.GetPatchType())
+		assert.Contains(t, string(patchAction.GetPatch()), `"cpu":"250m"`)
+		patched := testDeployment("250m")
+		patched.SetResourceVersion("2")
+		return true, patched, nil
+	})
+	client := patchOptionsClient{Interface: fakeClient, options: patchOptions}
+
+	events := fake.NewSimpleClientset()
+	impersonated := &authenticationv1.UserInfo{}
+	return &applier{
+		restMapper: kube.GetMockDynamicClient().RESTMapper,
+		clientFor: func(user authenticationv1.UserInfo) (dynamic.Interface, error) {
+			*impersonated = user
+			return client, nil
+		},
+		events: events,
+	}, events, impersonated
+}
+
+func testPatch() patch.Patch {
+	return patch.Patch{
+		Namespace: "team-a",
+		Kind:      "Deployment",
+		Name:      "web",
+		Containers: []patch.Container{{
+			Name: "app",
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")},
+			},
+		}},
+	}
+}
+
+func TestApplierApply(t *testing.T) {
+	var patchOptions []metav1.PatchOptions
+	a, events, impersonated := testApplier(t, &patchOptions)
+	user := authenticationv1.UserInfo{Username: "alice", Groups: []string{"dev"}}
+	expected := []resourceChange{{Container: "app", Resource: "requests.cpu", From: "100m", To: "250m"}}
+
+	// dry runs don't record events
+	changes, err := a.apply(context.TODO(), user, "apps/v1", testPatch(), applyRequest{Strategy: "guaranteed", DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, expected, changes)
+	assert.Equal(t, user, *impersonated)
+	assert.Empty(t, events.Actions())
+
+	changes, err = a.apply(context.TODO(), user, "apps/v1", testPatch(), applyRequest{Strategy: "guaranteed"})
+	require.NoError(t, err)
+	assert.Equal(t, expected, changes)
+	assert.Equal(t, []metav1.PatchOptions{
+		{FieldManager: applyFieldManager, DryRun: []string{metav1.DryRunAll}},
+		{FieldManager: applyFieldManager},
+	}, patchOptions)
+
+	recorded, err := events.CoreV1().Events("team-a").List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, recorded.Items, 1)
+	event := recorded.Items[0]
+	assert.Equal(t, applyEventReason, event.Reason)
+	assert.Equal(t, corev1.EventTypeNormal, event.Type)
+	assert.Equal(t, corev1.ObjectReference{
+		APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Namespace: "team-a", UID: "1234", ResourceVersion: "2",
+	}, event.InvolvedObject)
+	assert.Equal(t, "alice applied the guaranteed recommendation of goldilocks: app requests.cpu 100m -> 250m", event.Message)
+}
+
+func TestResourceChanges(t *testing.T) {
+	current := testDeployment("100m")
+	patched := testDeployment("250m")
+	require.NoError(t, unstructured.SetNestedSlice(patched.Object, []any{
+		map[string]any{"name": "app", "resources": map[string]any{
+			"requests": map[string]any{"cpu": "250m"},
+			"limits":   map[string]any{"memory": "256Mi"},
+		}},
+		map[string]any{"name": "sidecar"},
+	}, "spec", "template", "spec", "containers"))
+
+	changes := resourceChanges(current, patched, patch.Patch{Containers: []patch.Container{{Name: "app"}, {Name: "sidecar"}}})
+	assert.Equal(t, []resourceChange{
+		{Container: "app", Resource: "limits.memory", To: "256Mi"},
+		{Container: "app", Resource: "requests.cpu", From: "100m", To: "250m"},
+		{Container: "app", Resource: "requests.memory", From: "128Mi"},
+	}, changes)
+}
+
+func TestApplyRequestValidation(t *testing.T) {
+	opts := defaultOptions()
+	opts.Auth = testProxyAuth(kube.GetMockClient())
+	handler := applyRecommendation(*opts, &applier{})
+	tests := []struct {
+		name        string
+		contentType string
+		user        *authenticationv1.UserInfo
+		body        string
+		code        int
+	}{
+		{name: "form post", contentType: "application/x-www-form-urlencoded", user: &authenticationv1.UserInfo{Username: "alice"}, body: "strategy=guaranteed", code: http.StatusUnsupportedMediaType},
+		{name: "no user", contentType: "application/json", body: `{"strategy":"guaranteed"}`, code: http.StatusForbidden},
+		{name: "invalid body", contentType: "application/json", user: &authenticationv1.UserInfo{Username: "alice"}, body: `{`, code: http.StatusBadRequest},
+		{name: "unknown strategy", contentType: "application/json; charset=utf-8", user: &authenticationv1.UserInfo{Username: "alice"}, body: `{"strategy":"besteffort"}`, code: http.StatusBadRequest},
+		{name: "system group from proxy", contentType: "application/json", user: &authenticationv1.UserInfo{Username: "alice", Groups: []string{"system:masters"}}, body: `{"strategy":"guaranteed"}`, code: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/namespaces/team-a/workloads/Deployment/web/apply", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			r = mux.SetURLVars(r, map[string]string{"namespace": "team-a", "kind": "Deployment", "name": "web"})
+			if tt.user != nil {
+				r = r.WithContext(context.WithValue(r.Context(), userContextKey{}, *tt.user))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestApplyRoute(t *testing.T) {
+	kube.GetMockClient()
+	kube.GetMockDynamicClient()
+	router := GetRouter(EnableApply(true), WithAuth(testProxyAuth(kube.GetMockClient())))
+
+	// only posts are routed to apply
+	for method, routed := range map[string]bool{http.MethodPost: true, http.MethodGet: false} {
+		var match mux.RouteMatch
+		router.Match(httptest.NewRequest(method, "/api/v1/namespaces/team-a/workloads/Deployment/web/apply", nil), &match)
+		assert.Equal(t, routed, match.Route != nil && match.MatchErr == nil, method)
+	}
+
+	// applying needs authentication, and a verified proxy in the proxy mode
+	for name, setters := range map[string][]Option{
+		"no auth":             {EnableApply(true)},
+		"unverified proxy":    {EnableApply(true), WithAuth(NewAuth(kube.GetMockClient(), AuthProxy))},
+		"disabled with proxy": {WithAuth(testProxyAuth(kube.GetMockClient()))},
+	} {
+		var match mux.RouteMatch
+		assert.False(t, GetRouter(setters...).Match(httptest.NewRequest(http.MethodPost, "/api/v1/namespaces/team-a/workloads/Deployment/web/apply", nil), &match) && match.MatchErr == nil, name)
+	}
+}
+
+func TestImpersonationAllowed(t *testing.T) {
+	proxy := Options{Auth: testProxyAuth(kube.GetMockClient())}
+	token := Options{Auth: NewAuth(kube.GetMockClient(), AuthToken)}
+	tests := []struct {
+		name    string
+		opts    Options
+		user    authenticationv1.UserInfo
+		allowed bool
+	}{
+		{name: "proxy user", opts: proxy, user: authenticationv1.UserInfo{Username: "alice", Groups: []string{"dev"}}, allowed: true},
+		{name: "proxy system group", opts: proxy, user: authenticationv1.UserInfo{Username: "alice", Groups: []string{"dev", "system:masters"}}},
+		{name: "proxy system user", opts: proxy, user: authenticationv1.UserInfo{Username: "system:admin"}},
+		{name: "proxy service account", opts: proxy, user: authenticationv1.UserInfo{Username: "system:serviceaccount:kube-system:default"}},
+		// users of TokenReviews are authenticated by Kubernetes, and always in system:authenticated
+		{name: "token user", opts: token, user: authenticationv1.UserInfo{Username: "alice", Groups: []string{"system:authenticated"}}, allowed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := impersonationAllowed(tt.opts, tt.user)
+			assert.Equal(t, tt.allowed, err == nil, err)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+const dialog = document.getElementById("js-apply-dialog");
+const summary = document.getElementById("js-apply-summary");
+const diff = document.getElementById("js-apply-diff");
+const output = document.getElementById("js-apply-output");
+const confirmButton = document.getElementById("js-apply-confirm");
+
+let pending = null;
+
+if (!dialog || !summary || !diff || !output || !confirmButton) {
+    console.error("Could not find the apply dialog");
+} else {
+    document.querySelectorAll(".js-apply-button").forEach(button => {
+        button.addEventListener("click", () => preview(button.dataset));
+    });
+    confirmButton.addEventListener("click", confirm);
+}
+
+// apply posts the request, and returns the changes or throws the error of the response
+async function apply(target, dryRun) {
+    const body = { strategy: target.strategy, dryRun: dryRun };
+    if (target.container) {
+        body.containers = [target.container];
+    }
+
+    const response = await fetch(`api/v1/namespaces/${target.namespace}/workloads/${target.kind}/${target.name}/apply`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        credentials: "same-origin",
+        body: JSON.stringify(body)
+    });
+    if (!response.ok) {
+        throw new Error((await response.text()).trim() || response.statusText);
+    }
+    return response.json();
+}
+
+function describe(target) {
+    const containers = target.container ? `container ${target.container}` : "all containers";
+    return `The ${target.strategy} recommendation for ${containers} of ${target.kind} ${target.namespace}/${target.name}`;
+}
+
+function formatChanges(changes) {
+    if (!changes.length) {
+        return "No changes, the resources already match the recommendation.";
+    }
+    return changes.map(change => [
+        `${change.container} ${change.resource}`,
+        `- ${change.from || "unset"}`,
+        `+ ${change.to || "unset"}`
+    ].join("\n")).join("\n\n");
+}
+
+// preview shows the changes of a dry run, for the user to confirm
+async function preview(target) {
+    pending = null;
+    summary.textContent = describe(target);
+    diff.textContent = "Loading the changes…";
+    output.textContent = "";
+    confirmButton.disabled = true;
+    dialog.showModal();
+
+    try {
+        const result = await apply(target, true);
+        diff.textContent = formatChanges(result.changes);
+        if (result.changes.length) {
+            pending = target;
+            confirmButton.disabled = false;
+        }
+    } catch (error) {
+        diff.textContent = "";
+        output.textContent = `Could not preview the changes: ${error.message}`;
+    }
+}
+
+async function confirm() {
+    if (!pending) {
+        return;
+    }
+    const target = pending;
+    pending = null;
+    confirmButton.disabled = true;
+    output.textContent = "Applying…";
+
+    try {
+        await apply(target, false);
+        output.textContent = "Applied, reloading.";
+        window.location.reload();
+    } catch (error) {
+        output.textContent = `Could not apply the recommendation: ${error.message}`;
+    }
+}
@@ -0,0 +1,395 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/fairwindsops/goldilocks/pkg/kube"
+	"github.com/fairwindsops/goldilocks/pkg/summary"
+)
+
+// AuthMode is how the users of the dashboard are authenticated
+type AuthMode string
+
+const (
+	// AuthNone lets everyone see every namespace
+	AuthNone AuthMode = "none"
+	// AuthToken authenticates a bearer token with a TokenReview
+	AuthToken AuthMode = "token"
+	// AuthProxy trusts the user and groups headers set by an authenticating proxy, which must
+	// present a client certificate of the proxy client CA
+	AuthProxy AuthMode = "proxy"
+)
+
+// AuthModes are all the supported auth modes
+var AuthModes = []AuthMode{AuthNone, AuthToken, AuthProxy}
+
+// ParseAuthMode parses an auth mode, an empty mode is AuthNone
+func ParseAuthMode(value string) (AuthMode, error) {
+	if value == "" {
+		return AuthNone, nil
+	}
+	for _, mode := range AuthModes {
+		if AuthMode(value) == mode {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unknown auth mode %q, must be one of %v", value, AuthModes)
+}
+
+// tokenCacheTTL is how long the user of a token is remembered before it is reviewed again
+const tokenCacheTTL = time.Minute
+
+// accessCacheTTL is how long an access review is remembered before it is asked again
+const accessCacheTTL = time.Minute
+
+// Auth authenticates the users of the dashboard, and limits what they see to the
+// namespaces and workloads they can get
+type Auth struct {
+	Mode AuthMode
+	// TokenHeader is the header with the token in the token mode, e.g. X-Forwarded-Access-Token.
+	// The bearer token of the Authorization header is used if not set.
+	TokenHeader string
+	// UserHeader and GroupsHeader are the headers with the user and groups in the proxy mode
+	UserHeader   string
+	GroupsHeader string
+	// ProxyClientCA verifies the client certificate of the proxy in the proxy mode. The user and
+	// groups headers are only trusted from clients with a certificate it verified.
+	ProxyClientCA *x509.CertPool
+	// ProxyAllowedNames are the common names of the client certificates of the proxy, any if empty
+	ProxyAllowedNames []string
+
+	client *kube.ClientInstance
+
+	mu     sync.Mutex
+	tokens map[[sha256.Size]byte]cachedUser
+	// decisions are the access reviews of all users, pruned at most once per accessCacheTTL
+	decisions       map[accessKey]cachedDecision
+	decisionsPruned time.Time
+}
+
+type cachedUser struct {
+	user    authenticationv1.UserInfo
+	expires time.Time
+}
+
+// accessKey is an access review of a user, whose user info is hashed
+type accessKey struct {
+	user       [sha256.Size]byte
+	attributes authorizationv1.ResourceAttributes
+}
+
+type cachedDecision struct {
+	allowed bool
+	expires time.Time
+}
+
+// NewAuth returns an Auth that reviews tokens and access with the client
+func NewAuth(client *kube.ClientInstance, mode AuthMode) *Auth {
+	return &Auth{
+		Mode:         mode,
+		UserHeader:   "X-Forwarded-User",
+		GroupsHeader: "X-Forwarded-Groups",
+		client:       client,
+		tokens:       map[[sha256.Size]byte]cachedUser{},
+		decisions:    map[accessKey]cachedDecision{},
+	}
+}
+
+// enabled returns true if users are authenticated
+func (a *Auth) enabled() bool {
+	return a != nil && a.Mode != AuthNone && a.Mode != ""
+}
+
+type userContextKey struct{}
+
+// Protect only lets authenticated users through to the handler
+func (a *Auth) Protect(next http.Handler) http.Handler {
+	if !a.enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := a.authenticate(r)
+		if err != nil {
+			klog.V(2).Infof("Unauthenticated request for %s: %v", r.URL.Path, err)
+			if a.Mode == AuthToken {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="goldilocks"`)
+			}
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey{}, user)))
+	})
+}
+
+// authenticate returns the user of the request
+func (a *Auth) authenticate(r *http.Request) (authenticationv1.UserInfo, error) {
+	if a.Mode == AuthProxy {
+		if err := a.verifyProxy(r); err != nil {
+			return authenticationv1.UserInfo{}, err
+		}
+		user := authenticationv1.UserInfo{Username: r.Header.Get(a.UserHeader)}
+		if user.Username == "" {
+			return user, fmt.Errorf("no %s header", a.UserHeader)
+		}
+		for _, value := range r.Header.Values(a.GroupsHeader) {
+			for _, group := range strings.Split(value, ",") {
+				if group = strings.TrimSpace(group); group != "" {
+					user.Groups = append(user.Groups, group)
+				}
+			}
+		}
+		return user, nil
+	}
+
+	var token string
+	if a.TokenHeader != "" {
+		token = r.Header.Get(a.TokenHeader)
+	} else if value, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		token = strings.TrimSpace(value)
+	}
+	if token == "" {
+		return authenticationv1.UserInfo{}, errors.New("no token")
+	}
+	return a.reviewToken(r.Context(), token)
+}
+
+// verifyProxy returns an error unless the request was sent by the proxy, with a client certificate
+// that the server verified with the proxy client CA and one of the allowed names
+func (a *Auth) verifyProxy(r *http.Request) error {
+	if a.ProxyClientCA == nil {
+		return errors.New("no proxy client CA to verify the proxy with")
+	}
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return errors.New("no verified client certificate of the proxy")
+	}
+	name := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	if len(a.ProxyAllowedNames) > 0 && !slices.Contains(a.ProxyAllowedNames, name) {
+		return fmt.Errorf("client certificate of %q is not one of the allowed proxy names", name)
+	}
+	return nil
+}
+
+// stripUntrustedProxyHeaders removes the user and groups headers from requests that weren't sent by
+// the proxy, so that no handler can take them for the user
+func (a *Auth) stripUntrustedProxyHeaders(next http.Handler) http.Handler {
+	if a == nil || a.Mode != AuthProxy {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := a.verifyProxy(r); err != nil {
+			r.Header.Del(a.UserHeader)
+			r.Header.Del(a.GroupsHeader)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// reviewToken returns the user of the token, remembering it for the tokenCacheTTL
+func (a *Auth) reviewToken(ctx context.Context, token string) (authenticationv1.UserInfo, error) {
+	hash := sha256.Sum256([]byte(token))
+	a.mu.Lock()
+	cached, ok := a.tokens[hash]
+	a.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.user, nil
+	}
+
+	review, err := a.client.Client.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return authenticationv1.UserInfo{}, fmt.Errorf("reviewing token: %v", err)
+	}
+	if !review.Status.Authenticated {
+		return authenticationv1.UserInfo{}, fmt.Errorf("token not authenticated: %s", review.Status.Error)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	for key, cached := range a.tokens {
+		if now.After(cached.expires) {
+			delete(a.tokens, key)
+		}
+	}
+	a.tokens[hash] = cachedUser{user: review.Status.User, expires: now.Add(tokenCacheTTL)}
+	return review.Status.User, nil
+}
+
+// accessReviewer answers whether the user of a request can get resources, with the reviews remembered
+// by the Auth for the accessCacheTTL
+type accessReviewer struct {
+	ctx     context.Context
+	auth    *Auth
+	user    authenticationv1.UserInfo
+	userKey [sha256.Size]byte
+}
+
+// access returns the access reviewer of the user of the request, or nil if users are not authenticated
+func (a *Auth) access(r *http.Request) *accessReviewer {
+	if !a.enabled() {
+		return nil
+	}
+	user, _ := r.Context().Value(userContextKey{}).(authenticationv1.UserInfo)
+	return &accessReviewer{
+		ctx:     r.Context(),
+		auth:    a,
+		user:    user,
+		userKey: hashUser(user),
+	}
+}
+
+// hashUser returns the hash of everything access is reviewed for about a user
+func hashUser(user authenticationv1.UserInfo) [sha256.Size]byte {
+	groups := slices.Clone(user.Groups)
+	slices.Sort(groups)
+	user.Groups = groups
+	// maps are encoded with sorted keys
+	encoded, err := json.Marshal(user)
+	if err != nil {
+		klog.Errorf("Error encoding user %s: %v", user.Username, err)
+	}
+	return sha256.Sum256(encoded)
+}
+
+// decision returns the remembered access review, if it's not expired
+func (a *Auth) decision(key accessKey) (bool, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	cached, ok := a.decisions[key]
+	if !ok || time.Now().After(cached.expires) {
+		return false, false
+	}
+	return cached.allowed, true
+}
+
+// rememberDecision remembers the access review for the accessCacheTTL
+func (a *Auth) rememberDecision(key accessKey, allowed bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	if a.decisions == nil {
+		a.decisions = map[accessKey]cachedDecision{}
+	}
+	if now.Sub(a.decisionsPruned) > accessCacheTTL {
+		for key, cached := range a.decisions {
+			if now.After(cached.expires) {
+				delete(a.decisions, key)
+			}
+		}
+		a.decisionsPruned = now
+	}
+	a.decisions[key] = cachedDecision{allowed: allowed, expires: now.Add(accessCacheTTL)}
+}
+
+// canGet returns true if the user can get the resource. Failed reviews deny access, and aren't remembered.
+func (ar *accessReviewer) canGet(attributes authorizationv1.ResourceAttributes) bool {
+	attributes.Verb = "get"
+	key := accessKey{user: ar.userKey, attributes: attributes}
+	if allowed, ok := ar.auth.decision(key); ok {
+		return allowed
+	}
+
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, value := range ar.user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+	review, err := ar.auth.client.Client.AuthorizationV1().SubjectAccessReviews().Create(ar.ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:               ar.user.Username,
+			UID:                ar.user.UID,
+			Groups:             ar.user.Groups,
+			Extra:              extra,
+			ResourceAttributes: &attributes,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		klog.Errorf("Error reviewing access of %s to %s %s/%s: %v", ar.user.Username, attributes.Resource, attributes.Namespace, attributes.Name, err)
+		return false
+	}
+	ar.auth.rememberDecision(key, review.Status.Allowed)
+	return review.Status.Allowed
+}
+
+// canGetNamespace returns true if the user can get the namespace, or if users are not authenticated
+func (ar *accessReviewer) canGetNamespace(namespace string) bool {
+	if ar == nil {
+		return true
+	}
+	return ar.canGet(authorizationv1.ResourceAttributes{Namespace: namespace, Resource: "namespaces", Name: namespace})
+}
+
+// canGetWorkload returns true if the user can get the workload, or if users are not authenticated
+func (ar *accessReviewer) canGetWorkload(namespace string, workload summary.WorkloadSummary) bool {
+	if ar == nil {
+		return true
+	}
+	group, _, _ := strings.Cut(workload.APIVersion, "/")
+	if !strings.Contains(workload.APIVersion, "/") {
+		// the core group, e.g. v1 ReplicationControllers
+		group = ""
+	}
+	attributes := authorizationv1.ResourceAttributes{
+		Namespace: namespace,
+		Group:     group,
+		// the resource of the common workload kinds is their lower case plural
+		Resource: strings.ToLower(workload.ControllerType) + "s",
+	}
+	// users who can get every workload of the kind in the namespace need one review, not one per workload
+	if ar.canGet(attributes) {
+		return true
+	}
+	attributes.Name = workload.ControllerName
+	return ar.canGet(attributes)
+}
+
+// filterSummary returns the summary with only the namespaces and workloads the user can get
+func (ar *accessReviewer) filterSummary(data summary.Summary) summary.Summary {
+	if ar == nil {
+		return data
+	}
+	return summary.Filter(data, ar.canGetNamespace, ar.canGetWorkload)
+}
+
+// filterNamespaces returns the namespaces the user can get
+func (ar *accessReviewer) filterNamespaces(namespaces []string) []string {
+	if ar == nil {
+		return namespaces
+	}
+	var visible []string
+	for _, namespace := range namespaces {
+		if ar.canGetNamespace(namespace) {
+			visible = append(visible, namespace)
+		}
+	}
+	return visible
+}
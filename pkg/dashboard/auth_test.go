@@ -0,0 +1,273 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/fairwindsops/goldilocks/pkg/kube"
+	"github.com/fairwindsops/goldilocks/pkg/summary"
+)
+
+// testAuthClient authenticates the token "valid" as alice, and allows alice to get the namespace
+// "team-a" and the deployment "web" in it
+func testAuthClient() (*kube.ClientInstance, *int, *int) {
+	client := fake.NewSimpleClientset()
+	var tokenReviews, accessReviews int
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		tokenReviews++
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if review.Spec.Token == "valid" {
+			review.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{Username: "alice", Groups: []string{"team-a"}}}
+		}
+		return true, review, nil
+	})
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		accessReviews++
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		review.Status.Allowed = attributes.Verb == "get" && attributes.Namespace == "team-a" && (review.Spec.User == "alice" &&
+			((attributes.Resource == "namespaces" && attributes.Name == "team-a") ||
+				(attributes.Group == "apps" && attributes.Resource == "deployments" && attributes.Name == "web")) ||
+			// bob can get every deployment of team-a
+			review.Spec.User == "bob" && (attributes.Resource == "namespaces" && attributes.Name == "team-a" ||
+				attributes.Group == "apps" && attributes.Resource == "deployments"))
+		return true, review, nil
+	})
+	return &kube.ClientInstance{Client: client}, &tokenReviews, &accessReviews
+}
+
+func TestAuthProtect(t *testing.T) {
+	client, tokenReviews, _ := testAuthClient()
+	auth := NewAuth(client, AuthToken)
+
+	var user authenticationv1.UserInfo
+	handler := auth.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ = r.Context().Value(userContextKey{}).(authenticationv1.UserInfo)
+	}))
+
+	request := func(token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/api", nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := request("")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, http.StatusUnauthorized, request("invalid").Code)
+
+	assert.Equal(t, http.StatusOK, request("valid").Code)
+	assert.Equal(t, "alice", user.Username)
+	assert.Equal(t, http.StatusOK, request("valid").Code)
+	assert.Equal(t, 2, *tokenReviews, "reviewed tokens are cached")
+
+	// the token can come from a header of a proxy
+	auth.TokenHeader = "X-Forwarded-Access-Token"
+	r := httptest.NewRequest("GET", "/api", nil)
+	r.Header.Set("X-Forwarded-Access-Token", "valid")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAuthProxy(t *testing.T) {
+	client, tokenReviews, _ := testAuthClient()
+	auth := testProxyAuth(client)
+
+	r := fromProxy(httptest.NewRequest("GET", "/api", nil), "front-proxy")
+	r.Header.Set("X-Forwarded-User", "alice")
+	r.Header.Add("X-Forwarded-Groups", "team-a, team-b")
+	r.Header.Add("X-Forwarded-Groups", "admins")
+	user, err := auth.authenticate(r)
+	assert.NoError(t, err)
+	assert.Equal(t, authenticationv1.UserInfo{Username: "alice", Groups: []string{"team-a", "team-b", "admins"}}, user)
+	assert.Equal(t, 0, *tokenReviews)
+
+	_, err = auth.authenticate(fromProxy(httptest.NewRequest("GET", "/api", nil), "front-proxy"))
+	assert.Error(t, err)
+}
+
+func TestAuthProxyVerification(t *testing.T) {
+	client, _, _ := testAuthClient()
+	auth := testProxyAuth(client)
+	auth.ProxyAllowedNames = []string{"front-proxy"}
+
+	tests := []struct {
+		name     string
+		request  *http.Request
+		verified bool
+	}{
+		{name: "proxy", request: fromProxy(httptest.NewRequest("GET", "/api", nil), "front-proxy"), verified: true},
+		{name: "no tls", request: httptest.NewRequest("GET", "/api", nil)},
+		{name: "unverified certificate", request: func() *http.Request {
+			r := httptest.NewRequest("GET", "/api", nil)
+			r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "front-proxy"}}}}
+			return r
+		}()},
+		{name: "other name", request: fromProxy(httptest.NewRequest("GET", "/api", nil), "someone")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.request.Header.Set("X-Forwarded-User", "alice")
+			tt.request.Header.Set("X-Forwarded-Groups", "system:masters")
+			_, err := auth.authenticate(tt.request)
+			assert.Equal(t, tt.verified, err == nil, err)
+
+			// headers of other clients don't reach any handler, not even unprotected ones
+			var user, groups string
+			auth.stripUntrustedProxyHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				user, groups = r.Header.Get("X-Forwarded-User"), r.Header.Get("X-Forwarded-Groups")
+			})).ServeHTTP(httptest.NewRecorder(), tt.request)
+			assert.Equal(t, tt.verified, user != "" && groups != "")
+		})
+	}
+
+	// without a CA no client is the proxy
+	auth.ProxyClientCA = nil
+	_, err := auth.authenticate(withUser(fromProxy(httptest.NewRequest("GET", "/api", nil), "front-proxy"), "alice"))
+	assert.Error(t, err)
+}
+
+func TestAuthNone(t *testing.T) {
+	var auth *Auth
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	w := httptest.NewRecorder()
+	auth.Protect(handler).ServeHTTP(w, httptest.NewRequest("GET", "/api", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, auth.access(httptest.NewRequest("GET", "/api", nil)))
+	assert.Equal(t, []string{"a", "b"}, auth.access(nil).filterNamespaces([]string{"a", "b"}))
+}
+
+func TestAccessReviewer(t *testing.T) {
+	client, _, accessReviews := testAuthClient()
+	auth := testProxyAuth(client)
+
+	r := fromProxy(httptest.NewRequest("GET", "/api", nil), "front-proxy")
+	var access *accessReviewer
+	auth.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		access = auth.access(r)
+	})).ServeHTTP(httptest.NewRecorder(), withUser(r, "alice"))
+
+	assert.Equal(t, []string{"team-a"}, access.filterNamespaces([]string{"team-a", "team-b"}))
+
+	data := summary.Summary{Namespaces: map[string]summary.NamespaceSummary{
+		"team-a": {Namespace: "team-a", Workloads: map[string]summary.WorkloadSummary{
+			"apps/v1/Deployment/web": {APIVersion: "apps/v1", ControllerType: "Deployment", ControllerName: "web"},
+			"apps/v1/Deployment/api": {APIVersion: "apps/v1", ControllerType: "Deployment", ControllerName: "api"},
+		}},
+		"team-b": {Namespace: "team-b", Workloads: map[string]summary.WorkloadSummary{
+			"apps/v1/Deployment/web": {APIVersion: "apps/v1", ControllerType: "Deployment", ControllerName: "web"},
+		}},
+	}}
+	filtered := access.filterSummary(data)
+	assert.Len(t, filtered.Namespaces, 1)
+	assert.Len(t, filtered.Namespaces["team-a"].Workloads, 1)
+	assert.Contains(t, filtered.Namespaces["team-a"].Workloads, "apps/v1/Deployment/web")
+
+	// reviews are remembered across requests of the same user
+	reviews := *accessReviews
+	var again *accessReviewer
+	auth.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		again = auth.access(r)
+	})).ServeHTTP(httptest.NewRecorder(), withUser(fromProxy(httptest.NewRequest("GET", "/api", nil), "front-proxy"), "alice"))
+	assert.Equal(t, filtered, again.filterSummary(data))
+	assert.Equal(t, reviews, *accessReviews)
+
+	// but not for other users, or after the accessCacheTTL
+	r = withUser(fromProxy(httptest.NewRequest("GET", "/api", nil), "front-proxy"), "alice")
+	r.Header.Set("X-Forwarded-Groups", "team-a")
+	var grouped *accessReviewer
+	auth.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		grouped = auth.access(r)
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	grouped.filterNamespaces([]string{"team-a"})
+	assert.Equal(t, reviews+1, *accessReviews)
+	auth.mu.Lock()
+	for key, cached := range auth.decisions {
+		cached.expires = time.Now().Add(-time.Second)
+		auth.decisions[key] = cached
+	}
+	auth.mu.Unlock()
+	again.filterNamespaces([]string{"team-a"})
+	assert.Equal(t, reviews+2, *accessReviews)
+}
+
+func TestAccessReviewerNamespaceWide(t *testing.T) {
+	client, _, accessReviews := testAuthClient()
+	auth := testProxyAuth(client)
+
+	var access *accessReviewer
+	auth.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		access = auth.access(r)
+	})).ServeHTTP(httptest.NewRecorder(), withUser(fromProxy(httptest.NewRequest("GET", "/api", nil), "front-proxy"), "bob"))
+
+	workloads := map[string]summary.WorkloadSummary{}
+	for _, name := range []string{"web", "api", "worker", "cron"} {
+		workloads["apps/v1/Deployment/"+name] = summary.WorkloadSummary{APIVersion: "apps/v1", ControllerType: "Deployment", ControllerName: name}
+	}
+	filtered := access.filterSummary(summary.Summary{Namespaces: map[string]summary.NamespaceSummary{
+		"team-a": {Namespace: "team-a", Workloads: workloads},
+	}})
+	assert.Len(t, filtered.Namespaces["team-a"].Workloads, 4)
+	// one review of the namespace and one of all its deployments
+	assert.Equal(t, 2, *accessReviews)
+}
+
+// testProxyAuth returns an Auth of the proxy mode that trusts requests marked with fromProxy
+func testProxyAuth(client *kube.ClientInstance) *Auth {
+	auth := NewAuth(client, AuthProxy)
+	auth.ProxyClientCA = x509.NewCertPool()
+	return auth
+}
+
+// fromProxy marks the request as sent by the proxy, with a client certificate the server verified
+func fromProxy(r *http.Request, name string) *http.Request {
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: name}}}}}
+	return r
+}
+
+func withUser(r *http.Request, user string) *http.Request {
+	r.Header.Set("X-Forwarded-User", user)
+	return r
+}
+
+func TestParseAuthMode(t *testing.T) {
+	mode, err := ParseAuthMode("")
+	assert.NoError(t, err)
+	assert.Equal(t, AuthNone, mode)
+	mode, err = ParseAuthMode("token")
+	assert.NoError(t, err)
+	assert.Equal(t, AuthToken, mode)
+	_, err = ParseAuthMode("oidc")
+	assert.Error(t, err)
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGzip(t *testing.T) {
+	handler := Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			writeJSON(w, map[string]string{"hello": "world"})
+		case "/html":
+			_, _ = w.Write([]byte("<!DOCTYPE html><html></html>"))
+		case "/png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("png"))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+
+	tests := []struct {
+		path           string
+		acceptEncoding string
+		wantGzip       bool
+		wantBody       string
+	}{
+		{path: "/json", acceptEncoding: "gzip, deflate", wantGzip: true, wantBody: "{\"hello\":\"world\"}\n"},
+		{path: "/json", acceptEncoding: "", wantBody: "{\"hello\":\"world\"}\n"},
+		{path: "/json", acceptEncoding: "gzip;q=0", wantBody: "{\"hello\":\"world\"}\n"},
+		{path: "/html", acceptEncoding: "gzip", wantGzip: true, wantBody: "<!DOCTYPE html><html></html>"},
+		{path: "/png", acceptEncoding: "gzip", wantBody: "png"},
+		{path: "/empty", acceptEncoding: "gzip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.acceptEncoding, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+			body := io.Reader(w.Body)
+			if tt.wantGzip {
+				assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+				gz, err := gzip.NewReader(w.Body)
+				require.NoError(t, err)
+				body = gz
+			} else {
+				assert.Empty(t, w.Header().Get("Content-Encoding"))
+			}
+			got, err := io.ReadAll(body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBody, string(got))
+		})
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"github.com/fairwindsops/goldilocks/pkg/summary"
+)
+
+// exportFormat is the file format of an export of the recommendations
+type exportFormat string
+
+const (
+	exportCSV  exportFormat = "csv"
+	exportXLSX exportFormat = "xlsx"
+)
+
+var exportContentTypes = map[exportFormat]string{
+	exportCSV:  "text/csv; charset=utf-8",
+	exportXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// Export replies with the recommendations of every container of a namespace, or of all namespaces,
+// as a CSV or XLSX file. The summary is the one the dashboard shows for the same cost settings.
+func Export(opts Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		namespace := vars["namespace"]
+		format := exportFormat(vars["format"])
+		if _, ok := exportContentTypes[format]; !ok {
+			http.Error(w, fmt.Sprintf("unknown export format %q", format), http.StatusBadRequest)
+			return
+		}
+
+		vpaData, err := getVPAData(opts, r, namespace, r.URL.Query().Get("costPerCPU"), r.URL.Query().Get("costPerGB"))
+		if err != nil {
+			klog.Errorf("Error getting vpa data %v", err)
+			http.Error(w, "Error getting vpa data", http.StatusInternalServerError)
+			return
+		}
+
+		// write to a buffer first, so a failed export is an error instead of a truncated file
+		buf := &bytes.Buffer{}
+		if err := writeExport(buf, vpaData, format, opts.EnableCost); err != nil {
+			klog.Errorf("Error writing %s export: %v", format, err)
+			http.Error(w, "Error writing export", http.StatusInternalServerError)
+			return
+		}
+
+		name := namespace
+		if name == "" {
+			name = "all-namespaces"
+		}
+		w.Header().Set("Content-Type", exportContentTypes[format])
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="goldilocks-%s.%s"`, name, format))
+		if _, err := buf.WriteTo(w); err != nil {
+			klog.Errorf("Error writing export: %v", err)
+		}
+	})
+}
+
+// exportLinks returns the path of the exports of the namespace, of all namespaces for an empty namespace,
+// without the file extension, and the query with the cost settings of the request
+func exportLinks(r *http.Request, namespace string) (string, string) {
+	path := "export"
+	if namespace != "" {
+		path = "export/" + namespace
+	}
+
+	query := url.Values{}
+	for _, key := range []string{"costPerCPU", "costPerGB"} {
+		if value := r.URL.Query().Get(key); value != "" {
+			query.Set(key, value)
+		}
+	}
+	if len(query) == 0 {
+		return path, ""
+	}
+	return path, "?" + query.Encode()
+}
+
+// writeExport writes a row for every container of the summary in the format, with the cost columns
+// when costs are enabled and the summary has costs
+func writeExport(w io.Writer, data summary.Summary, format exportFormat, withCosts bool) error {
+	header, rows := exportRows(data, withCosts && data.Currency != "")
+	switch format {
+	case exportCSV:
+		return writeCSV(w, header, rows)
+	case exportXLSX:
+		return writeXLSX(w, "Recommendations", header, rows)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+// exportRows returns the header and a row for every container of the summary, sorted by namespace,
+// workload and container. Cells are strings, or numbers for the replicas and costs.
+func exportRows(data summary.Summary, withCosts bool) ([]string, [][]any) {
+	header := []string{
+		"Namespace", "Kind", "Workload", "Container", "Container Type", "Replicas",
+		"CPU Request", "CPU Limit", "Memory Request", "Memory Limit",
+		"CPU Target", "CPU Lower Bound", "CPU Upper Bound",
+		"Memory Target", "Memory Lower Bound", "Memory Upper Bound",
+	}
+	if withCosts {
+		unit := fmt.Sprintf(" (%s/%s)", data.Currency, data.CostPeriod)
+		header = append(header, "Current Cost"+unit, "Guaranteed Cost Change"+unit, "Burstable Cost Change"+unit)
+	}
+
+	var rows [][]any
+	for _, nsName := range sets.List(sets.KeySet(data.Namespaces)) {
+		ns := data.Namespaces[nsName]
+		for _, key := range sets.List(sets.KeySet(ns.Workloads)) {
+			workload := ns.Workloads[key]
+			for _, name := range sets.List(sets.KeySet(workload.Containers)) {
+				c := workload.Containers[name]
+				row := []any{
+					ns.Namespace, workload.ControllerType, workload.ControllerName, c.ContainerName, string(c.ContainerType), workload.Replicas,
+					exportQuantity(c.Requests, corev1.ResourceCPU), exportQuantity(c.Limits, corev1.ResourceCPU),
+					exportQuantity(c.Requests, corev1.ResourceMemory), exportQuantity(c.Limits, corev1.ResourceMemory),
+					exportQuantity(c.Target, corev1.ResourceCPU), exportQuantity(c.LowerBound, corev1.ResourceCPU), exportQuantity(c.UpperBound, corev1.ResourceCPU),
+					exportQuantity(c.Target, corev1.ResourceMemory), exportQuantity(c.LowerBound, corev1.ResourceMemory), exportQuantity(c.UpperBound, corev1.ResourceMemory),
+				}
+				if withCosts {
+					if c.Costs != nil {
+						row = append(row, c.Costs.Current.Total, c.Costs.GuaranteedChange(), c.Costs.BurstableChange())
+					} else {
+						row = append(row, "", "", "")
+					}
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	return header, rows
+}
+
+// exportQuantity formats the quantity of the resource as on the dashboard, empty if it is not set
+func exportQuantity(resources corev1.ResourceList, name corev1.ResourceName) string {
+	q, ok := resources[name]
+	if !ok {
+		return ""
+	}
+	return q.String()
+}
+
+// formatCell formats a cell of an export row
+func formatCell(cell any) string {
+	switch v := cell.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int32:
+		return strconv.Itoa(int(v))
+	}
+	return fmt.Sprint(cell)
+}
+
+func writeCSV(w io.Writer, header []string, rows [][]any) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, cell := range row {
+			record[i] = formatCell(cell)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
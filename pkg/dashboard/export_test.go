@@ -0,0 +1,154 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/fairwindsops/goldilocks/pkg/summary"
+)
+
+var testExportSummary = summary.Summary{
+	Currency:   "EUR",
+	CostPeriod: summary.CostPerMonth,
+	Namespaces: map[string]summary.NamespaceSummary{
+		"default": {Namespace: "default", Workloads: map[string]summary.WorkloadSummary{
+			"apps/v1/Deployment/web": {
+				APIVersion: "apps/v1", ControllerType: "Deployment", ControllerName: "web", Replicas: 2,
+				Containers: map[string]summary.ContainerSummary{
+					"nginx": {
+						ContainerName: "nginx",
+						ContainerType: summary.ContainerTypeContainer,
+						Requests:      corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m"), corev1.ResourceMemory: resource.MustParse("128Mi")},
+						Target:        corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("25m"), corev1.ResourceMemory: resource.MustParse("64Mi")},
+						LowerBound:    corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m"), corev1.ResourceMemory: resource.MustParse("32Mi")},
+						UpperBound:    corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1"), corev1.ResourceMemory: resource.MustParse("1Gi")},
+						Costs: &summary.ContainerCosts{
+							Current:    summary.Cost{Total: 10},
+							Guaranteed: summary.Cost{Total: 4.5},
+							Burstable:  summary.Cost{Total: 12},
+						},
+					},
+				},
+			},
+		}},
+		"empty": {Namespace: "empty", Workloads: map[string]summary.WorkloadSummary{}},
+	},
+}
+
+func TestWriteExportCSV(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, writeExport(buf, testExportSummary, exportCSV, true))
+	assert.Equal(t, "Namespace,Kind,Workload,Container,Container Type,Replicas,CPU Request,CPU Limit,Memory Request,Memory Limit,"+
+		"CPU Target,CPU Lower Bound,CPU Upper Bound,Memory Target,Memory Lower Bound,Memory Upper Bound,"+
+		"Current Cost (EUR/month),Guaranteed Cost Change (EUR/month),Burstable Cost Change (EUR/month)\n"+
+		"default,Deployment,web,nginx,container,2,100m,,128Mi,,25m,10m,1,64Mi,32Mi,1Gi,10,-5.5,2\n", buf.String())
+
+	// no cost columns when costs are disabled
+	buf.Reset()
+	require.NoError(t, writeExport(buf, testExportSummary, exportCSV, false))
+	assert.NotContains(t, buf.String(), "Cost")
+}
+
+func TestWriteExportXLSX(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, writeExport(buf, testExportSummary, exportXLSX, true))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	parts := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		parts[f.Name] = content
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		require.Contains(t, parts, name)
+		assert.NoError(t, xml.Unmarshal(parts[name], new(any)), name)
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Ref   string `xml:"r,attr"`
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	require.NoError(t, xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet))
+	require.Len(t, sheet.Rows, 2)
+	assert.Equal(t, "Namespace", sheet.Rows[0].Cells[0].Inline)
+	assert.Equal(t, "S1", sheet.Rows[0].Cells[18].Ref)
+
+	row := sheet.Rows[1]
+	assert.Equal(t, "2", row.Ref)
+	assert.Equal(t, "web", row.Cells[2].Inline)
+	// replicas and costs are numbers, unset limits have no cell
+	assert.Equal(t, "F2", row.Cells[5].Ref)
+	assert.Equal(t, "2", row.Cells[5].Value)
+	assert.Equal(t, "G2", row.Cells[6].Ref)
+	assert.Equal(t, "I2", row.Cells[7].Ref)
+	assert.Equal(t, "-5.5", row.Cells[len(row.Cells)-2].Value)
+}
+
+func TestCellReference(t *testing.T) {
+	assert.Equal(t, "A1", cellReference(0, 0))
+	assert.Equal(t, "Z2", cellReference(25, 1))
+	assert.Equal(t, "AA3", cellReference(26, 2))
+	assert.Equal(t, "AZ1", cellReference(51, 0))
+	assert.Equal(t, "BA1", cellReference(52, 0))
+}
+
+func TestExportLinks(t *testing.T) {
+	path, query := exportLinks(httptest.NewRequest("GET", "/dashboard?costPerCPU=1.5&costPerGB=2&other=x", nil), "")
+	assert.Equal(t, "export", path)
+	assert.Equal(t, "?costPerCPU=1.5&costPerGB=2", query)
+
+	path, query = exportLinks(httptest.NewRequest("GET", "/dashboard/default", nil), "default")
+	assert.Equal(t, "export/default", path)
+	assert.Empty(t, query)
+}
+
+func TestExportRoutes(t *testing.T) {
+	router := GetRouter(BasePath("/goldilocks/"))
+	for path, want := range map[string]map[string]string{
+		"/goldilocks/export.csv":          {"format": "csv"},
+		"/goldilocks/export/default.xlsx": {"namespace": "default", "format": "xlsx"},
+	} {
+		var match mux.RouteMatch
+		require.True(t, router.Match(httptest.NewRequest("GET", path, nil), &match), path)
+		assert.Equal(t, want, match.Vars, path)
+	}
+
+	var match mux.RouteMatch
+	router.Match(httptest.NewRequest("GET", "/goldilocks/export/default.pdf", nil), &match)
+	assert.Empty(t, match.Vars["format"])
+}
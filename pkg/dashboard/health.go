@@ -1,8 +1,11 @@
 package dashboard
 
 import (
+	"encoding/json"
 	"net/http"
 
+	"github.com/fairwindsops/goldilocks/pkg/summary"
+
 	"k8s.io/klog/v2"
 )
 
@@ -16,7 +19,20 @@ func Health(healthyMessage string) http.Handler {
 	})
 }
 
-// Healthz replies with a zero byte 200 response
-func Healthz() http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+// Healthz replies with a zero byte 200 response, or with the sync status of the summary cache
+// when there is one, with a 503 until the cache is synced or when it is stale
+func Healthz(cache *summary.Cache) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cache == nil {
+			return
+		}
+		status := cache.Status()
+		w.Header().Set("Content-Type", "application/json")
+		if !status.Healthy() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			klog.Errorf("Error writing healthcheck: %v", err)
+		}
+	})
 }
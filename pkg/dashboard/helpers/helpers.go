@@ -15,11 +15,15 @@
 package helpers
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/google/uuid"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/fairwindsops/goldilocks/pkg/utils"
 )
 
 func PrintResource(quant resource.Quantity) string {
@@ -30,6 +34,11 @@ func PrintResource(quant resource.Quantity) string {
 }
 
 func GetStatus(existing resource.Quantity, recommendation resource.Quantity, style string) string {
+	return GetToleratedStatus(utils.Tolerance{}, "", existing, recommendation, style)
+}
+
+// GetToleratedStatus is GetStatus where an existing value within the tolerance of the recommendation counts as equal
+func GetToleratedStatus(tolerance utils.Tolerance, resourceName corev1.ResourceName, existing resource.Quantity, recommendation resource.Quantity, style string) string {
 	if existing.IsZero() {
 		switch style {
 		case "text":
@@ -41,7 +50,7 @@ func GetStatus(existing resource.Quantity, recommendation resource.Quantity, sty
 		}
 	}
 
-	comparison := existing.Cmp(recommendation)
+	comparison := tolerance.Compare(resourceName, existing, recommendation)
 	if comparison == 0 {
 		switch style {
 		case "text":
@@ -76,6 +85,11 @@ func GetStatus(existing resource.Quantity, recommendation resource.Quantity, sty
 }
 
 func GetStatusRange(existing, lower, upper resource.Quantity, style string, resourceType string) string {
+	return GetToleratedStatusRange(utils.Tolerance{}, "", existing, lower, upper, style, resourceType)
+}
+
+// GetToleratedStatusRange is GetStatusRange where an existing value within the tolerance of a bound counts as equal to it
+func GetToleratedStatusRange(tolerance utils.Tolerance, resourceName corev1.ResourceName, existing, lower, upper resource.Quantity, style string, resourceType string) string {
 	if existing.IsZero() {
 		switch style {
 		case "text":
@@ -87,8 +101,8 @@ func GetStatusRange(existing, lower, upper resource.Quantity, style string, reso
 		}
 	}
 
-	comparisonLower := existing.Cmp(lower)
-	comparisonUpper := existing.Cmp(upper)
+	comparisonLower := tolerance.Compare(resourceName, existing, lower)
+	comparisonUpper := tolerance.Compare(resourceName, existing, upper)
 
 	if comparisonLower < 0 {
 		switch style {
@@ -157,3 +171,27 @@ func HasField(v any, name string) bool {
 	}
 	return rv.FieldByName(name).IsValid()
 }
+
+// ChartPoints scales the values into an SVG polyline points attribute for a
+// chart of the given width and height. The values share a scale from zero to max.
+func ChartPoints(values []float64, max, width, height float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	if max <= 0 {
+		max = 1
+	}
+
+	step := 0.0
+	if len(values) > 1 {
+		step = width / float64(len(values)-1)
+	}
+
+	points := make([]string, 0, len(values))
+	for i, v := range values {
+		x := step * float64(i)
+		y := height - (v/max)*height
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	return strings.Join(points, " ")
+}
@@ -253,3 +253,43 @@ func Test_GetStatusRange(t *testing.T) {
 		})
 	}
 }
+
+func Test_ChartPoints(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		max    float64
+		want   string
+	}{
+		{
+			name:   "empty",
+			values: nil,
+			max:    10,
+			want:   "",
+		},
+		{
+			name:   "single",
+			values: []float64{5},
+			max:    10,
+			want:   "0.0,50.0",
+		},
+		{
+			name:   "series",
+			values: []float64{0, 5, 10},
+			max:    10,
+			want:   "0.0,100.0 100.0,50.0 200.0,0.0",
+		},
+		{
+			name:   "zero max",
+			values: []float64{0, 0},
+			max:    0,
+			want:   "0.0,100.0 200.0,100.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ChartPoints(tt.values, tt.max, 200, 100)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+
+	"github.com/fairwindsops/goldilocks/pkg/dashboard/helpers"
+	"github.com/fairwindsops/goldilocks/pkg/history"
+)
+
+const (
+	chartWidth  = 600
+	chartHeight = 120
+)
+
+// HistoryAPI replies with the JSON history of a single workload
+func HistoryAPI(opts Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if opts.HistoryStore == nil {
+			http.Error(w, "History is not enabled", http.StatusNotFound)
+			return
+		}
+		vars := mux.Vars(r)
+		if !opts.Auth.access(r).canGetNamespace(vars["namespace"]) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		snapshots, err := opts.HistoryStore.Get(vars["namespace"], vars["kind"], vars["workload"])
+		if err != nil {
+			klog.Errorf("Error getting history data %v", err)
+			http.Error(w, "Error getting history data", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(snapshots); err != nil {
+			klog.Errorf("Error writing history data %v", err)
+			http.Error(w, "Error writing history data", http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// History replies with the rendered trend charts for a single workload
+func History(opts Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if opts.HistoryStore == nil {
+			http.Error(w, "History is not enabled", http.StatusNotFound)
+			return
+		}
+		vars := mux.Vars(r)
+		if !opts.Auth.access(r).canGetNamespace(vars["namespace"]) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		snapshots, err := opts.HistoryStore.Get(vars["namespace"], vars["kind"], vars["workload"])
+		if err != nil {
+			klog.Errorf("Error getting history data %v", err)
+			http.Error(w, "Error getting history data", http.StatusInternalServerError)
+			return
+		}
+
+		tmpl, err := getTemplate("history", opts,
+			"history",
+		)
+		if err != nil {
+			klog.Errorf("Error getting template data %v", err)
+			http.Error(w, "Error getting template data", http.StatusInternalServerError)
+			return
+		}
+
+		data := struct {
+			Namespace  string
+			Kind       string
+			Workload   string
+			Snapshots  int
+			Containers []containerTrend
+		}{
+			Namespace:  vars["namespace"],
+			Kind:       vars["kind"],
+			Workload:   vars["workload"],
+			Snapshots:  len(snapshots),
+			Containers: getContainerTrends(snapshots),
+		}
+
+		writeTemplate(tmpl, opts, &data, w)
+	})
+}
+
+// containerTrend holds the trend charts of a single container
+type containerTrend struct {
+	ContainerName string
+	Charts        []trendChart
+}
+
+// trendChart is a chart of the target and request of a single resource over time
+type trendChart struct {
+	Resource      string
+	Width         int
+	Height        int
+	TargetPoints  string
+	RequestPoints string
+	First         string
+	Last          string
+	LatestTarget  string
+	LatestRequest string
+}
+
+func getContainerTrends(snapshots []history.Snapshot) []containerTrend {
+	containerNames := map[string]bool{}
+	for _, snapshot := range snapshots {
+		for name := range snapshot.Containers {
+			containerNames[name] = true
+		}
+	}
+	names := make([]string, 0, len(containerNames))
+	for name := range containerNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	trends := make([]containerTrend, 0, len(names))
+	for _, name := range names {
+		trends = append(trends, containerTrend{
+			ContainerName: name,
+			Charts: []trendChart{
+				getTrendChart(snapshots, name, corev1.ResourceCPU),
+				getTrendChart(snapshots, name, corev1.ResourceMemory),
+			},
+		})
+	}
+	return trends
+}
+
+func getTrendChart(snapshots []history.Snapshot, containerName string, resourceName corev1.ResourceName) trendChart {
+	var targets, requests []float64
+	var latestTarget, latestRequest resource.Quantity
+	var max float64
+	chart := trendChart{
+		Resource: string(resourceName),
+		Width:    chartWidth,
+		Height:   chartHeight,
+	}
+
+	for _, snapshot := range snapshots {
+		c, ok := snapshot.Containers[containerName]
+		if !ok {
+			continue
+		}
+		if chart.First == "" {
+			chart.First = snapshot.Timestamp.Format("2006-01-02 15:04")
+		}
+		chart.Last = snapshot.Timestamp.Format("2006-01-02 15:04")
+
+		latestTarget = c.Target[resourceName]
+		latestRequest = c.Requests[resourceName]
+		target := quantityToFloat(latestTarget, resourceName)
+		request := quantityToFloat(latestRequest, resourceName)
+		targets = append(targets, target)
+		requests = append(requests, request)
+		if target > max {
+			max = target
+		}
+		if request > max {
+			max = request
+		}
+	}
+
+	chart.TargetPoints = helpers.ChartPoints(targets, max, chartWidth, chartHeight)
+	chart.RequestPoints = helpers.ChartPoints(requests, max, chartWidth, chartHeight)
+	chart.LatestTarget = helpers.PrintResource(latestTarget)
+	chart.LatestRequest = helpers.PrintResource(latestRequest)
+	return chart
+}
+
+func quantityToFloat(quantity resource.Quantity, resourceName corev1.ResourceName) float64 {
+	if resourceName == corev1.ResourceCPU {
+		return float64(quantity.MilliValue())
+	}
+	return float64(quantity.Value())
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sort"
 
 	"github.com/fairwindsops/goldilocks/pkg/kube"
 	"github.com/fairwindsops/goldilocks/pkg/utils"
@@ -15,19 +16,7 @@ import (
 // NamespaceList replies with the rendered namespace list of all goldilocks enabled namespaces
 func NamespaceList(opts Options) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var listOptions v1.ListOptions
-		if opts.OnByDefault || opts.ShowAllVPAs {
-			listOptions = v1.ListOptions{
-				LabelSelector: fmt.Sprintf("%s!=false", utils.VpaEnabledLabel),
-			}
-		} else {
-			listOptions = v1.ListOptions{
-				LabelSelector: labels.Set(map[string]string{
-					utils.VpaEnabledLabel: "true",
-				}).String(),
-			}
-		}
-		namespacesList, err := kube.GetInstance().Client.CoreV1().Namespaces().List(context.TODO(), listOptions)
+		namespaces, err := listNamespaces(opts, r)
 		if err != nil {
 			klog.Errorf("Error getting namespace list: %v", err)
 			http.Error(w, "Error getting namespace list", http.StatusInternalServerError)
@@ -55,11 +44,11 @@ func NamespaceList(opts Options) http.Handler {
 			}
 		}{}
 
-		for _, ns := range namespacesList.Items {
+		for _, name := range namespaces {
 			item := struct {
 				Name string
 			}{
-				Name: ns.Name,
+				Name: name,
 			}
 			data.Namespaces = append(data.Namespaces, item)
 		}
@@ -67,3 +56,43 @@ func NamespaceList(opts Options) http.Handler {
 		writeTemplate(tmpl, opts, &data, w)
 	})
 }
+
+// listNamespaces returns the names of the goldilocks enabled namespaces the user of the request can get
+func listNamespaces(opts Options, r *http.Request) ([]string, error) {
+	var listOptions v1.ListOptions
+	if opts.OnByDefault || opts.ShowAllVPAs {
+		listOptions = v1.ListOptions{
+			LabelSelector: fmt.Sprintf("%s!=false", utils.VpaEnabledLabel),
+		}
+	} else {
+		listOptions = v1.ListOptions{
+			LabelSelector: labels.Set(map[string]string{
+				utils.VpaEnabledLabel: "true",
+			}).String(),
+		}
+	}
+	var names []string
+	if opts.Cache.Healthy() {
+		selector, err := labels.Parse(listOptions.LabelSelector)
+		if err != nil {
+			return nil, err
+		}
+		namespaces, err := opts.Cache.Namespaces(selector)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range namespaces {
+			names = append(names, ns.Name)
+		}
+		sort.Strings(names)
+	} else {
+		namespacesList, err := kube.GetInstance().Client.CoreV1().Namespaces().List(context.TODO(), listOptions)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range namespacesList.Items {
+			names = append(names, ns.Name)
+		}
+	}
+	return opts.Auth.access(r).filterNamespaces(names), nil
+}
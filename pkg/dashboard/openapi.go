@@ -0,0 +1,273 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+
+	"k8s.io/klog/v2"
+
+	"github.com/fairwindsops/goldilocks/pkg/history"
+	"github.com/fairwindsops/goldilocks/pkg/summary"
+	"github.com/fairwindsops/goldilocks/pkg/utils"
+)
+
+const schemaRefPrefix = "#/components/schemas/"
+
+// OpenAPI replies with the OpenAPI document of the JSON API
+func OpenAPI(opts Options) http.Handler {
+	document, err := json.MarshalIndent(openAPIDocument(opts), "", "  ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			klog.Errorf("Error generating openapi document %v", err)
+			http.Error(w, "Error generating openapi document", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(document); err != nil {
+			klog.Errorf("Error writing openapi document %v", err)
+		}
+	})
+}
+
+// openAPIDocument describes the routes of the JSON API served by the router with the options.
+// The response schemas are generated from the types the routes reply with.
+func openAPIDocument(opts Options) map[string]any {
+	summaryParameters := append(append(append(append([]map[string]any{},
+		queryParameters(costParameters)...),
+		queryParameters(filterParameters)...),
+		queryParameters(pageParameters)...),
+		queryParameters([]apiParameter{fieldsParameter})...)
+	namespaceParameter := pathParameter("namespace", "Name of the namespace.")
+	jsonResponse := func(description string, schema map[string]any) map[string]any {
+		return map[string]any{
+			"description": description,
+			"content":     map[string]any{"application/json": map[string]any{"schema": schema}},
+		}
+	}
+	ref := func(name string) map[string]any {
+		return map[string]any{"$ref": schemaRefPrefix + name}
+	}
+	badRequest := map[string]any{"description": "Invalid query parameters."}
+
+	paths := map[string]any{
+		"/api": map[string]any{"get": map[string]any{
+			"operationId": "getSummary",
+			"summary":     "Summary of all namespaces",
+			"parameters":  summaryParameters,
+			"responses": map[string]any{
+				"200": jsonResponse("The summary. Workloads have only the selected fields with the fields parameter.", ref("Summary")),
+				"400": badRequest,
+			},
+		}},
+		"/api/{namespace}": map[string]any{"get": map[string]any{
+			"operationId": "getNamespaceSummary",
+			"summary":     "Summary of a namespace",
+			"parameters":  append([]map[string]any{namespaceParameter}, summaryParameters...),
+			"responses": map[string]any{
+				"200": jsonResponse("The summary. Workloads have only the selected fields with the fields parameter.", ref("Summary")),
+				"400": badRequest,
+			},
+		}},
+		"/api/{namespace}/{kind}/{name}": map[string]any{"get": map[string]any{
+			"operationId": "getWorkloadSummary",
+			"summary":     "Summary of a workload",
+			"parameters": append([]map[string]any{
+				namespaceParameter,
+				pathParameter("kind", "Kind of the workload, e.g. Deployment."),
+				pathParameter("name", "Name of the workload."),
+			}, append(queryParameters(costParameters), queryParameters([]apiParameter{fieldsParameter})...)...),
+			"responses": map[string]any{
+				"200": jsonResponse("The summary of the workload. It has only the selected fields with the fields parameter.", ref("WorkloadSummary")),
+				"400": badRequest,
+				"404": map[string]any{"description": "There is no summary of the workload."},
+			},
+		}},
+		"/api/namespaces": map[string]any{"get": map[string]any{
+			"operationId": "listNamespaces",
+			"summary":     "Namespaces shown on the dashboard",
+			"parameters":  queryParameters(pageParameters),
+			"responses": map[string]any{
+				"200": jsonResponse("The namespaces.", map[string]any{
+					"type":     "object",
+					"required": []string{"namespaces"},
+					"properties": map[string]any{"namespaces": map[string]any{
+						"type": "array",
+						"items": map[string]any{
+							"type":       "object",
+							"required":   []string{"name"},
+							"properties": map[string]any{"name": map[string]any{"type": "string"}},
+						},
+					}},
+				}),
+				"400": badRequest,
+			},
+		}},
+		"/api/schema.json": map[string]any{"get": map[string]any{
+			"operationId": "getSummarySchema",
+			"summary":     "JSON Schema of the summary",
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "The JSON Schema.",
+					"content":     map[string]any{"application/schema+json": map[string]any{"schema": map[string]any{"type": "object"}}},
+				},
+			},
+		}},
+		"/api/openapi.json": map[string]any{"get": map[string]any{
+			"operationId": "getOpenAPI",
+			"summary":     "This OpenAPI document",
+			"responses": map[string]any{
+				"200": jsonResponse("The OpenAPI document.", map[string]any{"type": "object"}),
+			},
+		}},
+	}
+
+	// the aliases under /api/v1/namespaces, which no namespace or workload name can shadow
+	alias := func(aliasPath, path string) {
+		operation := maps.Clone(paths[path].(map[string]any)["get"].(map[string]any))
+		operation["operationId"] = operation["operationId"].(string) + "V1"
+		operation["summary"] = fmt.Sprintf("%s, the same as %s", operation["summary"], path)
+		paths[aliasPath] = map[string]any{"get": operation}
+	}
+	alias("/api/v1/namespaces", "/api/namespaces")
+	alias("/api/v1/namespaces/{namespace}", "/api/{namespace}")
+	alias("/api/v1/namespaces/{namespace}/workloads/{kind}/{name}", "/api/{namespace}/{kind}/{name}")
+
+	schemaTypes := []any{summary.Summary{}, summary.WorkloadSummary{}}
+	if opts.HistoryStore != nil {
+		historyResponses := map[string]any{
+			"200": jsonResponse("The snapshots, oldest first.", map[string]any{"type": []string{"array", "null"}, "items": ref("Snapshot")}),
+		}
+		paths["/api/history/{namespace}/{workload}"] = map[string]any{"get": map[string]any{
+			"operationId": "getHistory",
+			"summary":     "Recommendation history of the workloads of every kind with a name",
+			"parameters":  []map[string]any{namespaceParameter, pathParameter("workload", "Name of the workload.")},
+			"responses":   historyResponses,
+		}}
+		paths["/api/history/{namespace}/{kind}/{workload}"] = map[string]any{"get": map[string]any{
+			"operationId": "getWorkloadHistory",
+			"summary":     "Recommendation history of a workload",
+			"parameters": []map[string]any{
+				namespaceParameter,
+				pathParameter("kind", "Kind of the workload, e.g. Deployment."),
+				pathParameter("workload", "Name of the workload."),
+			},
+			"responses": historyResponses,
+		}}
+		alias("/api/v1/namespaces/{namespace}/history/{workload}", "/api/history/{namespace}/{workload}")
+		alias("/api/v1/namespaces/{namespace}/workloads/{kind}/{workload}/history", "/api/history/{namespace}/{kind}/{workload}")
+		schemaTypes = append(schemaTypes, history.Snapshot{})
+	}
+	if opts.applyEnabled() {
+		stringProperty := map[string]any{"type": "string"}
+		paths["/api/v1/namespaces/{namespace}/workloads/{kind}/{name}/apply"] = map[string]any{"post": map[string]any{
+			"operationId": "applyRecommendation",
+			"summary":     "Apply the recommendation to a workload as the authenticated user",
+			"parameters": []map[string]any{
+				namespaceParameter,
+				pathParameter("kind", "Kind of the workload, e.g. Deployment."),
+				pathParameter("name", "Name of the workload."),
+			},
+			"requestBody": map[string]any{
+				"required": true,
+				"content": map[string]any{"application/json": map[string]any{"schema": map[string]any{
+					"type":     "object",
+					"required": []string{"strategy"},
+					"properties": map[string]any{
+						"strategy":   map[string]any{"type": "string", "enum": []string{utils.LimitStrategyGuaranteed, utils.LimitStrategyBurstable}},
+						"containers": map[string]any{"type": "array", "items": stringProperty, "description": "Containers to patch, all containers if empty."},
+						"dryRun":     map[string]any{"type": "boolean", "description": "Only return the changes."},
+					},
+				}}},
+			},
+			"responses": map[string]any{
+				"200": jsonResponse("The changed requests and limits.", map[string]any{
+					"type":     "object",
+					"required": []string{"namespace", "kind", "name", "strategy", "dryRun", "changes"},
+					"properties": map[string]any{
+						"namespace": stringProperty,
+						"kind":      stringProperty,
+						"name":      stringProperty,
+						"strategy":  stringProperty,
+						"dryRun":    map[string]any{"type": "boolean"},
+						"changes": map[string]any{"type": "array", "items": map[string]any{
+							"type":     "object",
+							"required": []string{"container", "resource"},
+							"properties": map[string]any{
+								"container": stringProperty,
+								"resource":  stringProperty,
+								"from":      stringProperty,
+								"to":        stringProperty,
+							},
+						}},
+					},
+				}),
+				"400": map[string]any{"description": "Invalid body, or the workload has no recommendation."},
+				"403": map[string]any{"description": "The user may not patch the workload."},
+				"404": map[string]any{"description": "There is no summary of the workload."},
+				"415": map[string]any{"description": "The body is not JSON."},
+			},
+		}}
+	}
+
+	components := map[string]any{"schemas": summary.Schemas(schemaRefPrefix, schemaTypes...)}
+	document := map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       "Goldilocks dashboard API",
+			"description": "Recommendations of the Vertical Pod Autoscalers managed by Goldilocks.",
+			"version":     summary.APIVersion,
+		},
+		"servers":    []map[string]any{{"url": opts.BasePath}},
+		"paths":      paths,
+		"components": components,
+	}
+
+	// the schemas are public, everything else needs a token
+	if opts.Auth.enabled() && opts.Auth.Mode == AuthToken {
+		components["securitySchemes"] = map[string]any{"bearer": map[string]any{"type": "http", "scheme": "bearer"}}
+		document["security"] = []map[string]any{{"bearer": []string{}}}
+		for _, path := range []string{"/api/schema.json", "/api/openapi.json"} {
+			paths[path].(map[string]any)["get"].(map[string]any)["security"] = []map[string]any{}
+		}
+	}
+	return document
+}
+
+func pathParameter(name, description string) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"in":          "path",
+		"required":    true,
+		"description": description,
+		"schema":      map[string]any{"type": "string"},
+	}
+}
+
+func queryParameters(parameters []apiParameter) []map[string]any {
+	described := make([]map[string]any, 0, len(parameters))
+	for _, parameter := range parameters {
+		described = append(described, map[string]any{
+			"name":        parameter.name,
+			"in":          "query",
+			"description": parameter.description,
+			"schema":      map[string]any{"type": parameter.schemaType},
+		})
+	}
+	return described
+}
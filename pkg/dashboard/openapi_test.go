@@ -0,0 +1,144 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fairwindsops/goldilocks/pkg/history"
+	"github.com/fairwindsops/goldilocks/pkg/kube"
+)
+
+type testOpenAPIOperation struct {
+	OperationID string `json:"operationId"`
+	Parameters  []struct {
+		Name string `json:"name"`
+		In   string `json:"in"`
+	} `json:"parameters"`
+}
+
+type testOpenAPIDocument struct {
+	Paths map[string]struct {
+		Get  *testOpenAPIOperation `json:"get"`
+		Post *testOpenAPIOperation `json:"post"`
+	} `json:"paths"`
+	Components struct {
+		Schemas map[string]json.RawMessage `json:"schemas"`
+	} `json:"components"`
+}
+
+var routeVariable = regexp.MustCompile(`\{([a-zA-Z]+):[^}]*\}`)
+
+// apiRoutes returns the path templates of the api routes of the router, relative to the base path
+func apiRoutes(t *testing.T, router *mux.Router, basePath string) []string {
+	var routes []string
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		template = strings.TrimPrefix(template, strings.TrimSuffix(basePath, "/"))
+		if template == "/api" || strings.HasPrefix(template, "/api/") {
+			routes = append(routes, routeVariable.ReplaceAllString(template, "{$1}"))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	sort.Strings(routes)
+	return routes
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+	}{
+		{name: "default"},
+		{name: "base path and history", options: []Option{BasePath("/goldilocks/"), WithHistory(&history.Store{})}},
+		{name: "apply", options: []Option{EnableApply(true), WithAuth(NewAuth(kube.GetMockClient(), AuthToken))}},
+	}
+	kube.GetMockDynamicClient()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions()
+			for _, setter := range tt.options {
+				setter(opts)
+			}
+			encoded, err := json.Marshal(openAPIDocument(*opts))
+			require.NoError(t, err)
+			var document testOpenAPIDocument
+			require.NoError(t, json.Unmarshal(encoded, &document))
+
+			// every api route is documented, and every documented path is a route
+			routes := apiRoutes(t, GetRouter(tt.options...), opts.BasePath)
+			var paths []string
+			for path := range document.Paths {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			assert.Equal(t, routes, paths)
+
+			// the path parameters are the variables of the route, and operations have unique ids
+			operationIDs := map[string]string{}
+			for path, item := range document.Paths {
+				var variables, parameters []string
+				for _, match := range regexp.MustCompile(`\{([a-zA-Z]+)\}`).FindAllStringSubmatch(path, -1) {
+					variables = append(variables, match[1])
+				}
+				operation := item.Get
+				if operation == nil {
+					operation = item.Post
+				}
+				require.NotNil(t, operation, path)
+				assert.NotContains(t, operationIDs, operation.OperationID, path)
+				operationIDs[operation.OperationID] = path
+				for _, parameter := range operation.Parameters {
+					if parameter.In == "path" {
+						parameters = append(parameters, parameter.Name)
+					}
+				}
+				assert.ElementsMatch(t, variables, parameters, path)
+			}
+
+			// every reference is to a schema of the document
+			for _, ref := range regexp.MustCompile(`"\$ref":"([^"]+)"`).FindAllStringSubmatch(string(encoded), -1) {
+				require.True(t, strings.HasPrefix(ref[1], schemaRefPrefix), ref[1])
+				assert.Contains(t, document.Components.Schemas, strings.TrimPrefix(ref[1], schemaRefPrefix))
+			}
+		})
+	}
+}
+
+func TestOpenAPIQueryParameters(t *testing.T) {
+	// the documented filter and page parameters are parsed
+	for _, parameter := range append(append([]apiParameter{}, filterParameters...), pageParameters...) {
+		if parameter.schemaType == "string" {
+			continue
+		}
+		_, err := parseAPIQuery(map[string][]string{parameter.name: {"not a number"}})
+		assert.Error(t, err, parameter.name)
+	}
+	q, err := parseAPIQuery(map[string][]string{fieldsParameter.name: {"controllerName"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"controllerName"}, q.fields)
+}
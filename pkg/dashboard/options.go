@@ -1,6 +1,8 @@
 package dashboard
 
 import (
+	"github.com/fairwindsops/goldilocks/pkg/history"
+	"github.com/fairwindsops/goldilocks/pkg/summary"
 	"github.com/fairwindsops/goldilocks/pkg/utils"
 	"k8s.io/apimachinery/pkg/util/sets"
 )
@@ -11,6 +13,10 @@ type Option func(*Options)
 // Options are options for getting and caching the Summarizer's VPAs
 type Options struct {
 	Port               int
+	BindAddress        string
+	TLSCertFile        string
+	TLSKeyFile         string
+	Timeouts           Timeouts
 	BasePath           string
 	VpaLabels          map[string]string
 	ExcludedContainers sets.Set[string]
@@ -18,18 +24,30 @@ type Options struct {
 	ShowAllVPAs        bool
 	InsightsHost       string
 	EnableCost         bool
+	HistoryStore       *history.Store
+	Tolerance          utils.Tolerance
+	Rounding           utils.RoundingPolicy
+	Headroom           utils.Headroom
+	LimitStrategy      utils.LimitStrategy
+	CostModel          *summary.CostModel
+	CostPeriod         summary.CostPeriod
+	Auth               *Auth
+	Cache              *summary.Cache
+	EnableApply        bool
 }
 
 // default options for the dashboard
 func defaultOptions() *Options {
 	return &Options{
 		Port:               8080,
+		Timeouts:           DefaultTimeouts,
 		BasePath:           "/",
 		VpaLabels:          utils.VPALabels,
 		ExcludedContainers: sets.Set[string]{},
 		OnByDefault:        false,
 		ShowAllVPAs:        false,
 		EnableCost:         true,
+		CostPeriod:         summary.CostPerHour,
 	}
 }
 
@@ -40,6 +58,29 @@ func OnPort(port int) Option {
 	}
 }
 
+// OnAddress is an Option for only listening on the address, e.g. 127.0.0.1, instead of on all addresses
+func OnAddress(address string) Option {
+	return func(opts *Options) {
+		opts.BindAddress = address
+	}
+}
+
+// WithTLS is an Option for serving the dashboard over TLS with the certificate and key file,
+// which are loaded again when they change
+func WithTLS(certFile, keyFile string) Option {
+	return func(opts *Options) {
+		opts.TLSCertFile = certFile
+		opts.TLSKeyFile = keyFile
+	}
+}
+
+// WithTimeouts is an Option for the timeouts of the dashboard server
+func WithTimeouts(timeouts Timeouts) Option {
+	return func(opts *Options) {
+		opts.Timeouts = timeouts
+	}
+}
+
 // ExcludeContainers is an Option for excluding containers in the dashboard summary
 func ExcludeContainers(excludedContainers sets.Set[string]) Option {
 	return func(opts *Options) {
@@ -84,3 +125,93 @@ func EnableCost(enableCost bool) Option {
 		opts.EnableCost = enableCost
 	}
 }
+
+// WithHistory is an Option for serving recommendation history from the store
+func WithHistory(store *history.Store) Option {
+	return func(opts *Options) {
+		opts.HistoryStore = store
+	}
+}
+
+// WithTolerance is an Option for treating current values within the tolerance of the recommendation as equal
+func WithTolerance(tolerance utils.Tolerance) Option {
+	return func(opts *Options) {
+		opts.Tolerance = tolerance
+	}
+}
+
+// WithRoundingPolicy is an Option for rounding the recommendations shown on the dashboard
+func WithRoundingPolicy(rounding utils.RoundingPolicy) Option {
+	return func(opts *Options) {
+		opts.Rounding = rounding
+	}
+}
+
+// WithHeadroom is an Option for adding a safety margin on top of the recommendations
+// of workloads and namespaces without a headroom annotation
+func WithHeadroom(headroom utils.Headroom) Option {
+	return func(opts *Options) {
+		opts.Headroom = headroom
+	}
+}
+
+// WithLimitStrategy is an Option for deriving recommended requests and limits
+// in namespaces without a limit strategy annotation
+func WithLimitStrategy(strategy utils.LimitStrategy) Option {
+	return func(opts *Options) {
+		opts.LimitStrategy = strategy
+	}
+}
+
+// WithCostModel is an Option for showing costs from a pricing catalog instead of the cost settings
+func WithCostModel(model *summary.CostModel) Option {
+	return func(opts *Options) {
+		opts.CostModel = model
+	}
+}
+
+// WithCostPeriod is an Option for the period costs are shown for
+func WithCostPeriod(period summary.CostPeriod) Option {
+	return func(opts *Options) {
+		opts.CostPeriod = period
+	}
+}
+
+// WithAuth is an Option for authenticating users and only showing them the namespaces and workloads they can get
+func WithAuth(auth *Auth) Option {
+	return func(opts *Options) {
+		opts.Auth = auth
+	}
+}
+
+// WithCache is an Option for serving summaries from a summary cache once it is synced
+func WithCache(cache *summary.Cache) Option {
+	return func(opts *Options) {
+		opts.Cache = cache
+	}
+}
+
+// EnableApply is an Option for letting authenticated users apply recommendations to their workloads.
+// Applying is only enabled with authentication, and in the proxy mode only with a proxy client CA.
+func EnableApply(enableApply bool) Option {
+	return func(opts *Options) {
+		opts.EnableApply = enableApply
+	}
+}
+
+// applyEnabled is whether recommendations can be applied from the dashboard. Users from the headers
+// of a proxy that isn't verified could be anyone, so they never apply recommendations.
+func (opts Options) applyEnabled() bool {
+	if !opts.EnableApply || !opts.Auth.enabled() {
+		return false
+	}
+	return opts.Auth.Mode != AuthProxy || opts.Auth.ProxyClientCA != nil
+}
+
+// costCurrency is the currency of the pricing catalog, or the currency of the cost settings without a catalog
+func (opts Options) costCurrency() string {
+	if opts.CostModel != nil {
+		return opts.CostModel.Currency()
+	}
+	return summary.DefaultCurrency
+}
@@ -32,10 +32,12 @@ func GetRouter(setters ...Option) *mux.Router {
 	}
 
 	router := mux.NewRouter().PathPrefix(strings.TrimSuffix(opts.BasePath, "/")).Subrouter().StrictSlash(true)
+	// no route sees user headers that didn't come from the proxy
+	router.Use(opts.Auth.stripUntrustedProxyHeaders)
 
 	// health
 	router.Handle("/health", Health("OK"))
-	router.Handle("/healthz", Healthz())
+	router.Handle("/healthz", Healthz(opts.Cache))
 
 	// assets
 	router.Handle("/favicon.ico", Asset("assets/images/favicon-32x32.png"))
@@ -52,12 +54,19 @@ func GetRouter(setters ...Option) *mux.Router {
 	fileServer := http.FileServerFS(subAssetsFS)
 	router.PathPrefix("/static/").Handler(http.StripPrefix(path.Join(opts.BasePath, "/static/"), fileServer))
 
+	// everything but the health checks and assets is only for authenticated users
+	protect := opts.Auth.Protect
+
 	// dashboard
-	router.Handle("/dashboard", Dashboard(*opts))
-	router.Handle("/dashboard/{namespace:[a-zA-Z0-9-]+}", Dashboard(*opts))
+	router.Handle("/dashboard", protect(Dashboard(*opts)))
+	router.Handle("/dashboard/{namespace:[a-zA-Z0-9-]+}", protect(Dashboard(*opts)))
+
+	// exports of the dashboard
+	router.Handle("/export.{format:csv|xlsx}", protect(Export(*opts)))
+	router.Handle("/export/{namespace:[a-zA-Z0-9-]+}.{format:csv|xlsx}", protect(Export(*opts)))
 
 	// namespace list
-	router.Handle("/namespaces", NamespaceList(*opts))
+	router.Handle("/namespaces", protect(NamespaceList(*opts)))
 
 	// root
 	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -73,7 +82,33 @@ func GetRouter(setters ...Option) *mux.Router {
 		http.Redirect(w, r, path.Join(opts.BasePath, "/namespaces"), http.StatusMovedPermanently)
 	})
 
-	// api
-	router.Handle("/api/{namespace:[a-zA-Z0-9-]+}", API(*opts))
+	// api, the paths with fixed segments are registered before the namespace and workload paths they
+	// would otherwise match. The same endpoints are under /api/v1/namespaces, where no name shadows them.
+	router.Handle("/api", protect(API(*opts)))
+	router.Handle("/api/schema.json", Schema())
+	router.Handle("/api/openapi.json", OpenAPI(*opts))
+	router.Handle("/api/namespaces", protect(NamespacesAPI(*opts)))
+	router.Handle("/api/{namespace:[a-zA-Z0-9-]+}", protect(API(*opts)))
+	router.Handle("/api/v1/namespaces", protect(NamespacesAPI(*opts)))
+	router.Handle("/api/v1/namespaces/{namespace:[a-zA-Z0-9-]+}", protect(API(*opts)))
+	router.Handle("/api/v1/namespaces/{namespace:[a-zA-Z0-9-]+}/workloads/{kind:[a-zA-Z]+}/{name:[a-zA-Z0-9-.]+}", protect(WorkloadAPI(*opts)))
+
+	// history
+	if opts.HistoryStore != nil {
+		router.Handle("/history/{namespace:[a-zA-Z0-9-]+}/{workload:[a-zA-Z0-9-.]+}", protect(History(*opts)))
+		router.Handle("/history/{namespace:[a-zA-Z0-9-]+}/{kind:[a-zA-Z]+}/{workload:[a-zA-Z0-9-.]+}", protect(History(*opts)))
+		router.Handle("/api/history/{namespace:[a-zA-Z0-9-]+}/{workload:[a-zA-Z0-9-.]+}", protect(HistoryAPI(*opts)))
+		router.Handle("/api/history/{namespace:[a-zA-Z0-9-]+}/{kind:[a-zA-Z]+}/{workload:[a-zA-Z0-9-.]+}", protect(HistoryAPI(*opts)))
+		router.Handle("/api/v1/namespaces/{namespace:[a-zA-Z0-9-]+}/history/{workload:[a-zA-Z0-9-.]+}", protect(HistoryAPI(*opts)))
+		router.Handle("/api/v1/namespaces/{namespace:[a-zA-Z0-9-]+}/workloads/{kind:[a-zA-Z]+}/{workload:[a-zA-Z0-9-.]+}/history", protect(HistoryAPI(*opts)))
+	}
+
+	// apply, with the permissions of the user
+	if opts.applyEnabled() {
+		router.Handle("/api/v1/namespaces/{namespace:[a-zA-Z0-9-]+}/workloads/{kind:[a-zA-Z]+}/{name:[a-zA-Z0-9-.]+}/apply", protect(Apply(*opts))).Methods(http.MethodPost)
+	}
+
+	// registered after the history api, which has the same number of path segments
+	router.Handle("/api/{namespace:[a-zA-Z0-9-]+}/{kind:[a-zA-Z]+}/{name:[a-zA-Z0-9-.]+}", protect(WorkloadAPI(*opts)))
 	return router
 }
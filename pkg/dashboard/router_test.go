@@ -0,0 +1,88 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fairwindsops/goldilocks/pkg/history"
+	"github.com/fairwindsops/goldilocks/pkg/kube"
+)
+
+func TestRouterNameCollisions(t *testing.T) {
+	kube.GetMockDynamicClient()
+	router := GetRouter(WithHistory(&history.Store{}), EnableApply(true), WithAuth(testProxyAuth(kube.GetMockClient())))
+
+	const (
+		namespaceAPI       = "/api/{namespace:[a-zA-Z0-9-]+}"
+		namespacesAPI      = "/api/namespaces"
+		workloadAPI        = "/api/{namespace:[a-zA-Z0-9-]+}/{kind:[a-zA-Z]+}/{name:[a-zA-Z0-9-.]+}"
+		namespaceAPIV1     = "/api/v1/namespaces/{namespace:[a-zA-Z0-9-]+}"
+		namespacesAPIV1    = "/api/v1/namespaces"
+		workloadAPIV1      = "/api/v1/namespaces/{namespace:[a-zA-Z0-9-]+}/workloads/{kind:[a-zA-Z]+}/{name:[a-zA-Z0-9-.]+}"
+		historyAPI         = "/api/history/{namespace:[a-zA-Z0-9-]+}/{workload:[a-zA-Z0-9-.]+}"
+		kindHistoryAPI     = "/api/history/{namespace:[a-zA-Z0-9-]+}/{kind:[a-zA-Z]+}/{workload:[a-zA-Z0-9-.]+}"
+		historyAPIV1       = "/api/v1/namespaces/{namespace:[a-zA-Z0-9-]+}/history/{workload:[a-zA-Z0-9-.]+}"
+		workloadHistoryAPI = "/api/v1/namespaces/{namespace:[a-zA-Z0-9-]+}/workloads/{kind:[a-zA-Z]+}/{workload:[a-zA-Z0-9-.]+}/history"
+		applyAPI           = "/api/v1/namespaces/{namespace:[a-zA-Z0-9-]+}/workloads/{kind:[a-zA-Z]+}/{name:[a-zA-Z0-9-.]+}/apply"
+	)
+	tests := []struct {
+		method   string
+		path     string
+		template string
+		vars     map[string]string
+	}{
+		// the fixed paths are matched before the namespace and workload paths
+		{path: "/api/namespaces", template: namespacesAPI, vars: map[string]string{}},
+		{path: "/api/team-a", template: namespaceAPI, vars: map[string]string{"namespace": "team-a"}},
+		{path: "/api/team-a/Deployment/web", template: workloadAPI, vars: map[string]string{"namespace": "team-a", "kind": "Deployment", "name": "web"}},
+		{path: "/api/history/team-a/web", template: historyAPI, vars: map[string]string{"namespace": "team-a", "workload": "web"}},
+		{path: "/api/history/team-a/Deployment/web", template: kindHistoryAPI, vars: map[string]string{"namespace": "team-a", "kind": "Deployment", "workload": "web"}},
+		{path: "/api/history", template: namespaceAPI, vars: map[string]string{"namespace": "history"}},
+		{path: "/api/v1", template: namespaceAPI, vars: map[string]string{"namespace": "v1"}},
+		// namespaces named like the fixed segments are served under /api/v1/namespaces
+		{path: "/api/v1/namespaces", template: namespacesAPIV1, vars: map[string]string{}},
+		{path: "/api/v1/namespaces/namespaces", template: namespaceAPIV1, vars: map[string]string{"namespace": "namespaces"}},
+		{path: "/api/v1/namespaces/history/workloads/Deployment/web", template: workloadAPIV1, vars: map[string]string{"namespace": "history", "kind": "Deployment", "name": "web"}},
+		{path: "/api/v1/namespaces/workloads/history/web", template: historyAPIV1, vars: map[string]string{"namespace": "workloads", "workload": "web"}},
+		// workloads named like the fixed segments of the api
+		{path: "/api/v1/namespaces/team-a/workloads/Deployment/history", template: workloadAPIV1, vars: map[string]string{"namespace": "team-a", "kind": "Deployment", "name": "history"}},
+		{path: "/api/v1/namespaces/team-a/workloads/Deployment/apply", template: workloadAPIV1, vars: map[string]string{"namespace": "team-a", "kind": "Deployment", "name": "apply"}},
+		{path: "/api/v1/namespaces/team-a/history/workloads", template: historyAPIV1, vars: map[string]string{"namespace": "team-a", "workload": "workloads"}},
+		{path: "/api/v1/namespaces/team-a/workloads/Deployment/history/history", template: workloadHistoryAPI, vars: map[string]string{"namespace": "team-a", "kind": "Deployment", "workload": "history"}},
+		{method: http.MethodPost, path: "/api/v1/namespaces/history/workloads/Deployment/apply/apply", template: applyAPI, vars: map[string]string{"namespace": "history", "kind": "Deployment", "name": "apply"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			var match mux.RouteMatch
+			require.True(t, router.Match(httptest.NewRequest(method, tt.path, nil), &match))
+			require.NoError(t, match.MatchErr)
+			template, err := match.Route.GetPathTemplate()
+			require.NoError(t, err)
+			assert.Equal(t, tt.template, template)
+			assert.Equal(t, tt.vars, match.Vars)
+		})
+	}
+}
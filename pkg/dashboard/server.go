@@ -0,0 +1,129 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Timeouts are the timeouts of the dashboard server, see http.Server
+type Timeouts struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+	// Shutdown is how long requests in flight are waited for when the server shuts down
+	Shutdown time.Duration
+}
+
+// DefaultTimeouts are the timeouts of the dashboard server unless set with WithTimeouts
+var DefaultTimeouts = Timeouts{
+	ReadHeader: 10 * time.Second,
+	Read:       30 * time.Second,
+	Write:      60 * time.Second,
+	Idle:       120 * time.Second,
+	Shutdown:   20 * time.Second,
+}
+
+// NewServer returns the server of the dashboard, with its HTML and JSON responses compressed
+func NewServer(setters ...Option) (*http.Server, error) {
+	opts := defaultOptions()
+	for _, setter := range setters {
+		setter(opts)
+	}
+
+	server := &http.Server{
+		Addr:              net.JoinHostPort(opts.BindAddress, strconv.Itoa(opts.Port)),
+		Handler:           Gzip(GetRouter(setters...)),
+		ReadHeaderTimeout: opts.Timeouts.ReadHeader,
+		ReadTimeout:       opts.Timeouts.Read,
+		WriteTimeout:      opts.Timeouts.Write,
+		IdleTimeout:       opts.Timeouts.Idle,
+	}
+
+	if opts.TLSCertFile != "" || opts.TLSKeyFile != "" {
+		if opts.TLSCertFile == "" || opts.TLSKeyFile == "" {
+			return nil, errors.New("both a TLS certificate and key file are needed for TLS")
+		}
+		reloader, err := newCertificateReloader(opts.TLSCertFile, opts.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+	}
+
+	// the proxy is authenticated with its client certificate, other clients don't need one
+	if opts.Auth.enabled() && opts.Auth.Mode == AuthProxy {
+		if opts.Auth.ProxyClientCA == nil || server.TLSConfig == nil {
+			return nil, errors.New("the proxy auth mode needs TLS and a proxy client CA to verify the proxy with")
+		}
+		server.TLSConfig.ClientCAs = opts.Auth.ProxyClientCA
+		server.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return server, nil
+}
+
+// ListenAndServe serves the dashboard until the context is done, then shuts the server down gracefully,
+// waiting for the requests in flight up to the shutdown timeout
+func ListenAndServe(ctx context.Context, setters ...Option) error {
+	opts := defaultOptions()
+	for _, setter := range setters {
+		setter(opts)
+	}
+
+	server, err := NewServer(setters...)
+	if err != nil {
+		return err
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if server.TLSConfig != nil {
+			klog.Infof("Serving the dashboard over TLS on %s", server.Addr)
+			serveErr <- server.ListenAndServeTLS("", "")
+		} else {
+			klog.Infof("Serving the dashboard on %s", server.Addr)
+			serveErr <- server.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	klog.Infof("Shutting down the dashboard, waiting up to %s for requests in flight", opts.Timeouts.Shutdown)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), opts.Timeouts.Shutdown)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertificate writes a self-signed certificate for the common name and its key to the files
+func writeCertificate(t *testing.T, certFile, keyFile, commonName string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	_, err := newCertificateReloader(certFile, keyFile)
+	assert.Error(t, err)
+
+	writeCertificate(t, certFile, keyFile, "first")
+	reloader, err := newCertificateReloader(certFile, keyFile)
+	require.NoError(t, err)
+	commonName := func() string {
+		certificate, err := reloader.GetCertificate(nil)
+		require.NoError(t, err)
+		parsed, err := x509.ParseCertificate(certificate.Certificate[0])
+		require.NoError(t, err)
+		return parsed.Subject.CommonName
+	}
+	assert.Equal(t, "first", commonName())
+
+	// a broken certificate is not served
+	require.NoError(t, os.WriteFile(keyFile, []byte("broken"), 0o600))
+	require.NoError(t, os.Chtimes(keyFile, time.Now(), time.Now().Add(time.Minute)))
+	reloader.checked = time.Time{}
+	assert.Equal(t, "first", commonName())
+
+	// changed files are only checked once per interval
+	writeCertificate(t, certFile, keyFile, "second")
+	require.NoError(t, os.Chtimes(keyFile, time.Now(), time.Now().Add(2*time.Minute)))
+	assert.Equal(t, "first", commonName())
+	reloader.checked = time.Time{}
+	assert.Equal(t, "second", commonName())
+}
+
+func TestNewServer(t *testing.T) {
+	_, err := NewServer(WithTLS("tls.crt", ""))
+	assert.Error(t, err)
+
+	server, err := NewServer(OnAddress("127.0.0.1"), OnPort(9090), WithTimeouts(Timeouts{Read: time.Second}))
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:9090", server.Addr)
+	assert.Equal(t, time.Second, server.ReadTimeout)
+	assert.Nil(t, server.TLSConfig)
+}
+
+func TestNewServerProxyAuth(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeCertificate(t, certFile, keyFile, "dashboard")
+	auth := NewAuth(nil, AuthProxy)
+
+	// the proxy can't be verified without TLS and its client CA
+	_, err := NewServer(WithAuth(auth))
+	assert.Error(t, err)
+	_, err = NewServer(WithAuth(auth), WithTLS(certFile, keyFile))
+	assert.Error(t, err)
+
+	_, err = LoadCertPool(keyFile)
+	assert.Error(t, err)
+	auth.ProxyClientCA, err = LoadCertPool(certFile)
+	require.NoError(t, err)
+	server, err := NewServer(WithAuth(auth), WithTLS(certFile, keyFile))
+	require.NoError(t, err)
+	assert.Equal(t, auth.ProxyClientCA, server.TLSConfig.ClientCAs)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, server.TLSConfig.ClientAuth)
+}
+
+func TestListenAndServeShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- ListenAndServe(ctx, OnAddress("127.0.0.1"), OnPort(0))
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server did not shut down")
+	}
+}
@@ -10,6 +10,9 @@ import (
 	"strings"
 
 	"github.com/fairwindsops/goldilocks/pkg/dashboard/helpers"
+	"github.com/fairwindsops/goldilocks/pkg/summary"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/klog/v2"
 )
 
@@ -25,6 +28,7 @@ const (
 	EmailTemplateName       = "email.gohtml"
 	ApiTokenTemplateName    = "api_token.gohtml"
 	CostSettingTemplateName = "cost_settings.gohtml"
+	HistoryTemplateName     = "history.gohtml"
 )
 
 var (
@@ -57,9 +61,20 @@ func getTemplate(name string, opts Options, includedTemplates ...string) (*templ
 		"printResource":  helpers.PrintResource,
 		"getStatus":      helpers.GetStatus,
 		"getStatusRange": helpers.GetStatusRange,
-		"resourceName":   helpers.ResourceName,
-		"getUUID":        helpers.GetUUID,
-		"hasField":       helpers.HasField,
+		"getToleratedStatus": func(resourceName string, existing, recommendation resource.Quantity, style string) string {
+			return helpers.GetToleratedStatus(opts.Tolerance, corev1.ResourceName(resourceName), existing, recommendation, style)
+		},
+		"getToleratedStatusRange": func(resourceName string, existing, lower, upper resource.Quantity, style string, resourceType string) string {
+			return helpers.GetToleratedStatusRange(opts.Tolerance, corev1.ResourceName(resourceName), existing, lower, upper, style, resourceType)
+		},
+		"resourceName": helpers.ResourceName,
+		"getUUID":      helpers.GetUUID,
+		"hasField":     helpers.HasField,
+		"formatCost": func(cost float64) string {
+			return summary.FormatCost(cost, opts.costCurrency(), opts.CostPeriod)
+		},
+
+		"applyEnabled": opts.applyEnabled,
 
 		"opts": func() Options {
 			return opts
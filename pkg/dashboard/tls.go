@@ -0,0 +1,118 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// certificateCheckInterval is how often the certificate and key files are checked for changes
+const certificateCheckInterval = 10 * time.Second
+
+// certificateReloader serves the certificate of a certificate and key file, and loads them again
+// when they change, e.g. when cert-manager renews the certificate of a mounted secret
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+	// loaded are the modification times of the certificate and key file that were loaded
+	loaded  [2]time.Time
+	checked time.Time
+}
+
+// newCertificateReloader returns a certificateReloader of the certificate and key file, which must be loadable
+func newCertificateReloader(certFile, keyFile string) (*certificateReloader, error) {
+	reloader := &certificateReloader{certFile: certFile, keyFile: keyFile}
+	modTimes, err := reloader.modTimes()
+	if err != nil {
+		return nil, err
+	}
+	if err := reloader.load(modTimes); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// GetCertificate returns the certificate, loaded again if the files changed. The certificate that
+// was loaded last is kept while the files can't be loaded, e.g. while only one of them was replaced.
+func (r *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) < certificateCheckInterval {
+		return r.certificate, nil
+	}
+	r.checked = time.Now()
+
+	modTimes, err := r.modTimes()
+	if err != nil {
+		klog.Errorf("Error checking the TLS certificate for changes: %v", err)
+		return r.certificate, nil
+	}
+	if modTimes != r.loaded {
+		if err := r.load(modTimes); err != nil {
+			klog.Errorf("Error reloading the TLS certificate, serving the previous certificate: %v", err)
+		} else {
+			klog.Infof("Reloaded the TLS certificate from %s", r.certFile)
+		}
+	}
+	return r.certificate, nil
+}
+
+// load loads the certificate and key file with the modification times
+func (r *certificateReloader) load(modTimes [2]time.Time) error {
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("unable to load the TLS certificate %s and key %s: %w", r.certFile, r.keyFile, err)
+	}
+	r.certificate = &certificate
+	r.loaded = modTimes
+	return nil
+}
+
+// modTimes returns the modification times of the certificate and key file
+func (r *certificateReloader) modTimes() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+	for i, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return modTimes, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// LoadCertPool returns the pool of the PEM encoded CA certificates of the file
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, fmt.Errorf("no PEM encoded certificates in %s", file)
+	}
+	return pool, nil
+}
@@ -0,0 +1,302 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package history
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/fairwindsops/goldilocks/pkg/summary"
+)
+
+// Snapshot is the recorded state of a single workload's recommendations at a point in time
+type Snapshot struct {
+	Timestamp      time.Time                    `json:"timestamp"`
+	Namespace      string                       `json:"namespace"`
+	ControllerName string                       `json:"controllerName"`
+	ControllerType string                       `json:"controllerType"`
+	Containers     map[string]ContainerSnapshot `json:"containers"`
+}
+
+// ContainerSnapshot is the recorded state of a single container's recommendations
+type ContainerSnapshot struct {
+	LowerBound corev1.ResourceList `json:"lowerBound,omitempty"`
+	UpperBound corev1.ResourceList `json:"upperBound,omitempty"`
+	Target     corev1.ResourceList `json:"target,omitempty"`
+	Requests   corev1.ResourceList `json:"requests,omitempty"`
+	Limits     corev1.ResourceList `json:"limits,omitempty"`
+}
+
+// Store is a local store of summary snapshots in a BoltDB file. Snapshots are kept in a
+// bucket per namespace and workload name, ordered by time, so that the history of a workload
+// is read without scanning the others. A second bucket indexes them by time for the retention.
+type Store struct {
+	db        *bolt.DB
+	retention time.Duration
+}
+
+var (
+	// workloadsBucket has a bucket per namespace, with a bucket per workload name of its snapshots,
+	// keyed by the time and the kind of the workload
+	workloadsBucket = []byte("workloads")
+	// timesBucket indexes the snapshots by time, keyed by the time, namespace, name and kind
+	timesBucket = []byte("times")
+)
+
+// openTimeout is how long Open waits for another process to close the file
+const openTimeout = 10 * time.Second
+
+// Open loads (or creates) the store at the given path. Snapshots older than
+// the retention are dropped. A retention of zero keeps snapshots forever.
+func Open(path string, retention time.Duration) (*Store, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{workloadsBucket, timesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	store := &Store{
+		db:        db,
+		retention: retention,
+	}
+	if err := store.prune(time.Now()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// Close closes the file of the store
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Record takes a snapshot of every workload in the summary
+func (s *Store) Record(data summary.Summary, now time.Time) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		workloads, times := tx.Bucket(workloadsBucket), tx.Bucket(timesBucket)
+		for _, ns := range data.Namespaces {
+			for _, workload := range ns.Workloads {
+				snapshot := Snapshot{
+					Timestamp:      now.UTC(),
+					Namespace:      ns.Namespace,
+					ControllerName: workload.ControllerName,
+					ControllerType: workload.ControllerType,
+					Containers:     map[string]ContainerSnapshot{},
+				}
+				for name, c := range workload.Containers {
+					snapshot.Containers[name] = ContainerSnapshot{
+						LowerBound: c.LowerBound,
+						UpperBound: c.UpperBound,
+						Target:     c.Target,
+						Requests:   c.Requests,
+						Limits:     c.Limits,
+					}
+				}
+				value, err := json.Marshal(snapshot)
+				if err != nil {
+					return err
+				}
+
+				namespaceBucket, err := workloads.CreateBucketIfNotExists([]byte(snapshot.Namespace))
+				if err != nil {
+					return err
+				}
+				workloadBucket, err := namespaceBucket.CreateBucketIfNotExists([]byte(snapshot.ControllerName))
+				if err != nil {
+					return err
+				}
+				if err := workloadBucket.Put(snapshotKey(snapshot.Timestamp, snapshot.ControllerType), value); err != nil {
+					return err
+				}
+				if err := times.Put(timeKey(snapshot), nil); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.prune(now)
+}
+
+// Get returns the snapshots of a single workload, oldest first. An empty kind returns the
+// snapshots of the workloads of every kind with that name.
+func (s *Store) Get(namespace, kind, workload string) ([]Snapshot, error) {
+	snapshots := []Snapshot{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		namespaceBucket := tx.Bucket(workloadsBucket).Bucket([]byte(namespace))
+		if namespaceBucket == nil {
+			return nil
+		}
+		workloadBucket := namespaceBucket.Bucket([]byte(workload))
+		if workloadBucket == nil {
+			return nil
+		}
+		return workloadBucket.ForEach(func(key, value []byte) error {
+			if kind != "" && string(key[timestampLength:]) != kind {
+				return nil
+			}
+			var snapshot Snapshot
+			if err := json.Unmarshal(value, &snapshot); err != nil {
+				return fmt.Errorf("unreadable history snapshot of %s/%s: %w", namespace, workload, err)
+			}
+			snapshots = append(snapshots, snapshot)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// prune deletes the snapshots outside of the retention, found in order of time with the time index
+func (s *Store) prune(now time.Time) error {
+	if s.retention <= 0 {
+		return nil
+	}
+
+	cutoff := timestamp(now.Add(-s.retention))
+	pruned := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		workloads, times := tx.Bucket(workloadsBucket), tx.Bucket(timesBucket)
+		// collected first, deleting under a cursor would skip keys
+		var expired [][]byte
+		cursor := times.Cursor()
+		for key, _ := cursor.First(); key != nil && bytes.Compare(key[:timestampLength], cutoff) < 0; key, _ = cursor.Next() {
+			expired = append(expired, bytes.Clone(key))
+		}
+		for _, key := range expired {
+			parts := bytes.SplitN(key[timestampLength:], []byte{0}, 3)
+			if len(parts) != 3 {
+				return fmt.Errorf("invalid history time index key %q", key)
+			}
+			if namespaceBucket := workloads.Bucket(parts[0]); namespaceBucket != nil {
+				if workloadBucket := namespaceBucket.Bucket(parts[1]); workloadBucket != nil {
+					if err := workloadBucket.Delete(append(key[:timestampLength:timestampLength], parts[2]...)); err != nil {
+						return err
+					}
+					// workloads that are gone don't leave empty buckets behind
+					if first, _ := workloadBucket.Cursor().First(); first == nil {
+						if err := namespaceBucket.DeleteBucket(parts[1]); err != nil {
+							return err
+						}
+					}
+				}
+			}
+			if err := times.Delete(key); err != nil {
+				return err
+			}
+		}
+		pruned = len(expired)
+		return nil
+	})
+	if pruned > 0 {
+		klog.V(3).Infof("pruned %d history snapshots older than %s", pruned, now.Add(-s.retention))
+	}
+	return err
+}
+
+// timestampLength is the length of the timestamps at the start of the keys
+const timestampLength = 8
+
+// timestamp encodes the time so that the byte order of the keys is the order of time
+func timestamp(t time.Time) []byte {
+	key := make([]byte, timestampLength)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
+
+// snapshotKey is the key of a snapshot in the bucket of its workload
+func snapshotKey(t time.Time, kind string) []byte {
+	return append(timestamp(t), kind...)
+}
+
+// timeKey is the key of a snapshot in the time index
+func timeKey(snapshot Snapshot) []byte {
+	key := timestamp(snapshot.Timestamp)
+	key = append(key, snapshot.Namespace...)
+	key = append(key, 0)
+	key = append(key, snapshot.ControllerName...)
+	key = append(key, 0)
+	return append(key, snapshot.ControllerType...)
+}
+
+// Recorder periodically records summaries into a Store
+type Recorder struct {
+	Store     *Store
+	Interval  time.Duration
+	Summarize func() (summary.Summary, error)
+}
+
+// Run records a snapshot immediately and then on every interval until the context is done
+func (r Recorder) Run(ctx context.Context) error {
+	if r.Interval <= 0 {
+		return fmt.Errorf("history interval must be greater than zero, got %s", r.Interval)
+	}
+
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		r.record()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r Recorder) record() {
+	data, err := r.Summarize()
+	if err != nil {
+		klog.Errorf("Error getting summary for history: %v", err)
+		return
+	}
+	if err := r.Store.Record(data, time.Now()); err != nil {
+		klog.Errorf("Error recording history: %v", err)
+		return
+	}
+	klog.V(3).Info("recorded summary snapshot into history")
+}
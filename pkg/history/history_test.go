@@ -0,0 +1,125 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package history
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/fairwindsops/goldilocks/pkg/summary"
+)
+
+const testSummaryJSON = `{
+  "apiVersion": "goldilocks.fairwinds.com/v1",
+  "namespaces": {
+    "testing": {
+      "namespace": "testing",
+      "workloads": {
+        "test-basic": {
+          "controllerName": "test-basic",
+          "controllerType": "Deployment",
+          "containers": {
+            "container": {
+              "containerName": "container",
+              "target": {"cpu": "100m", "memory": "100Mi"},
+              "requests": {"cpu": "50m", "memory": "64Mi"}
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+func testSummary(t *testing.T) summary.Summary {
+	var data summary.Summary
+	require.NoError(t, json.Unmarshal([]byte(testSummaryJSON), &data))
+	return data
+}
+
+func TestStore_RecordAndGet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	store, err := Open(path, 0)
+	require.NoError(t, err)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, store.Record(testSummary(t), start.Add(time.Hour)))
+	assert.NoError(t, store.Record(testSummary(t), start))
+
+	got, err := store.Get("testing", "", "test-basic")
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+	assert.Equal(t, start, got[0].Timestamp)
+	assert.Equal(t, start.Add(time.Hour), got[1].Timestamp)
+	assert.Equal(t, "Deployment", got[0].ControllerType)
+	target := got[0].Containers["container"].Target[corev1.ResourceCPU]
+	assert.Equal(t, 0, target.Cmp(resource.MustParse("100m")))
+
+	assertSnapshots(t, store, "testing", "Deployment", "test-basic", 2)
+	assertSnapshots(t, store, "testing", "CronJob", "test-basic", 0)
+	assertSnapshots(t, store, "testing", "", "missing", 0)
+	assertSnapshots(t, store, "missing", "", "test-basic", 0)
+
+	// re-opening the store reads the snapshots from disk
+	require.NoError(t, store.Close())
+	reopened, err := Open(path, 0)
+	require.NoError(t, err)
+	defer reopened.Close()
+	assertSnapshots(t, reopened, "testing", "", "test-basic", 2)
+}
+
+func TestStore_Retention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	store, err := Open(path, 24*time.Hour)
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+	other := testSummary(t)
+	other.Namespaces["testing"].Workloads["test-basic"] = summary.WorkloadSummary{ControllerName: "gone", ControllerType: "CronJob"}
+	assert.NoError(t, store.Record(other, now.Add(-72*time.Hour)))
+	assert.NoError(t, store.Record(testSummary(t), now.Add(-48*time.Hour)))
+	assert.NoError(t, store.Record(testSummary(t), now))
+
+	assertSnapshots(t, store, "testing", "", "test-basic", 1)
+	assertSnapshots(t, store, "testing", "", "gone", 0)
+
+	// the time index and the buckets of workloads without snapshots are pruned too
+	require.NoError(t, store.db.View(func(tx *bolt.Tx) error {
+		assert.Equal(t, 1, tx.Bucket(timesBucket).Stats().KeyN)
+		assert.Nil(t, tx.Bucket(workloadsBucket).Bucket([]byte("testing")).Bucket([]byte("gone")))
+		return nil
+	}))
+
+	require.NoError(t, store.Close())
+	reopened, err := Open(path, 24*time.Hour)
+	require.NoError(t, err)
+	defer reopened.Close()
+	assertSnapshots(t, reopened, "testing", "", "test-basic", 1)
+}
+
+func assertSnapshots(t *testing.T, store *Store, namespace, kind, workload string, count int) {
+	t.Helper()
+	got, err := store.Get(namespace, kind, workload)
+	require.NoError(t, err)
+	assert.Len(t, got, count)
+}
@@ -160,6 +160,17 @@ func getKubeClientDynamic() dynamic.Interface {
 	return clientset
 }
 
+// GetImpersonatingDynamicClient returns a dynamic client that acts as the impersonated user,
+// so requests are authorized with the RBAC of the user instead of the service account
+func GetImpersonatingDynamicClient(impersonate rest.ImpersonationConfig) (dynamic.Interface, error) {
+	kubeConf, err := config.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	kubeConf.Impersonate = impersonate
+	return dynamic.NewForConfig(kubeConf)
+}
+
 func getRESTMapper() meta.RESTMapper {
 	kubeConf, err := config.GetConfig()
 	if err != nil {
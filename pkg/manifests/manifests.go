@@ -0,0 +1,452 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+
+	"github.com/fairwindsops/goldilocks/pkg/patch"
+)
+
+// indent is the indentation used for newly inserted mappings
+const indent = "  "
+
+// Change is a single resource value that was rewritten in a manifest
+type Change struct {
+	File      string
+	Kind      string
+	Namespace string
+	Name      string
+	Container string
+	Field     string
+	From      string
+	To        string
+}
+
+// String returns a human readable description of the change
+func (c Change) String() string {
+	from := c.From
+	if from == "" {
+		from = "<not set>"
+	}
+	return fmt.Sprintf("%s: %s %s/%s container %s %s: %s -> %s", c.File, c.Kind, c.Namespace, c.Name, c.Container, c.Field, from, c.To)
+}
+
+// Apply walks the directory for YAML manifests and rewrites the container resources of every
+// workload with a patch. Comments and formatting of the files are preserved. Files are only
+// written when dryRun is false.
+func Apply(dir string, patches []patch.Patch, dryRun bool) ([]Change, error) {
+	var changes []Change
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rewritten, fileChanges, err := Rewrite(content, patches)
+		if err != nil {
+			klog.Warningf("skipping %s: %v", path, err)
+			return nil
+		}
+		for i := range fileChanges {
+			fileChanges[i].File = path
+		}
+		changes = append(changes, fileChanges...)
+
+		if dryRun || len(fileChanges) == 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, rewritten, info.Mode().Perm())
+	})
+	return changes, err
+}
+
+// Rewrite applies the patches to the workloads in the (multi-document) YAML content
+func Rewrite(content []byte, patches []patch.Patch) ([]byte, []Change, error) {
+	r := &rewriter{
+		inserts: map[int][]string{},
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		r.rewriteDocument(document.Content[0], patches)
+	}
+
+	if len(r.changes) == 0 {
+		return content, nil, nil
+	}
+	return r.apply(content), r.changes, nil
+}
+
+// replacement replaces length bytes at the 1-based line and column with text
+type replacement struct {
+	line   int
+	column int
+	length int
+	text   string
+}
+
+type rewriter struct {
+	replacements []replacement
+	// lines to insert after the given 1-based line
+	inserts map[int][]string
+	changes []Change
+}
+
+func (r *rewriter) rewriteDocument(root *yaml.Node, patches []patch.Patch) {
+	kind := scalarValue(root, "kind")
+	metadata := mappingValue(root, "metadata")
+	if kind == "" || metadata == nil {
+		return
+	}
+	name := scalarValue(metadata, "name")
+	namespace := scalarValue(metadata, "namespace")
+
+	p, ok := findPatch(patches, kind, namespace, name)
+	if !ok {
+		return
+	}
+
+	podSpecPath := []string{"spec", "template", "spec"}
+	if kind == "CronJob" {
+		podSpecPath = []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	}
+	podSpec := root
+	for _, key := range podSpecPath {
+		podSpec = mappingValue(podSpec, key)
+		if podSpec == nil {
+			return
+		}
+	}
+
+	// native sidecars and init containers are matched by name like the other containers
+	for _, field := range []string{"containers", "initContainers"} {
+		containers := mappingValue(podSpec, field)
+		if containers == nil || containers.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, container := range containers.Content {
+			containerName := scalarValue(container, "name")
+			for _, c := range p.Containers {
+				if c.Name != containerName {
+					continue
+				}
+				change := Change{
+					Kind:      kind,
+					Namespace: p.Namespace,
+					Name:      name,
+					Container: containerName,
+				}
+				if err := r.rewriteContainer(container, c.Resources, change); err != nil {
+					klog.Warningf("unable to rewrite resources of %s %s/%s container %s: %v", kind, p.Namespace, name, containerName, err)
+				}
+			}
+		}
+	}
+}
+
+// findPatch finds the patch for the workload. Manifests without a namespace match a
+// patch by kind and name only, as long as that is unambiguous.
+func findPatch(patches []patch.Patch, kind, namespace, name string) (patch.Patch, bool) {
+	var matches []patch.Patch
+	for _, p := range patches {
+		if p.Kind != kind || p.Name != name {
+			continue
+		}
+		if namespace != "" && p.Namespace != namespace {
+			continue
+		}
+		matches = append(matches, p)
+	}
+	if len(matches) != 1 {
+		if len(matches) > 1 {
+			klog.Warningf("%s %s has no namespace and matches workloads in %d namespaces, skipping", kind, name, len(matches))
+		}
+		return patch.Patch{}, false
+	}
+	return matches[0], true
+}
+
+func (r *rewriter) rewriteContainer(container *yaml.Node, resources corev1.ResourceRequirements, change Change) error {
+	sections := map[string]corev1.ResourceList{
+		"requests": resources.Requests,
+		"limits":   resources.Limits,
+	}
+
+	nameKey, _ := mappingEntry(container, "name")
+	resourcesKey, resourcesValue := mappingEntry(container, "resources")
+	switch {
+	case resourcesKey == nil:
+		// insert the whole resources block right after the container name
+		lines := []string{"resources:"}
+		for _, section := range sortedKeys(sections) {
+			lines = append(lines, r.sectionLines(indent, section, sections[section], change)...)
+		}
+		r.insert(nameKey.Line, nameKey.Column-1, lines)
+		return nil
+	case isEmpty(resourcesValue):
+		r.clearEmpty(resourcesValue)
+		var lines []string
+		for _, section := range sortedKeys(sections) {
+			lines = append(lines, r.sectionLines("", section, sections[section], change)...)
+		}
+		r.insert(resourcesKey.Line, resourcesKey.Column-1+len(indent), lines)
+		return nil
+	case resourcesValue.Kind != yaml.MappingNode || resourcesValue.Style&yaml.FlowStyle != 0:
+		return fmt.Errorf("resources must be a block mapping")
+	}
+
+	for _, section := range sortedKeys(sections) {
+		recommended := sections[section]
+		if len(recommended) == 0 {
+			continue
+		}
+		sectionKey, sectionValue := mappingEntry(resourcesValue, section)
+		switch {
+		case sectionKey == nil:
+			column := resourcesValue.Content[0].Column - 1
+			r.insert(resourcesKey.Line, column, r.sectionLines("", section, recommended, change))
+			continue
+		case isEmpty(sectionValue):
+			r.clearEmpty(sectionValue)
+			r.insert(sectionKey.Line, sectionKey.Column-1+len(indent), r.resourceLines(section, recommended, nil, change))
+			continue
+		case sectionValue.Kind != yaml.MappingNode || sectionValue.Style&yaml.FlowStyle != 0:
+			return fmt.Errorf("resources.%s must be a block mapping", section)
+		}
+
+		var missing []corev1.ResourceName
+		for _, name := range sortedResourceNames(recommended) {
+			quantity := recommended[name]
+			_, value := mappingEntry(sectionValue, string(name))
+			if value == nil {
+				missing = append(missing, name)
+				continue
+			}
+			existing, err := resource.ParseQuantity(value.Value)
+			if err == nil && existing.Cmp(quantity) == 0 {
+				continue
+			}
+			r.replace(value, quantity.String())
+			r.record(change, section+"."+string(name), value.Value, quantity.String())
+		}
+		if len(missing) > 0 {
+			column := sectionValue.Content[0].Column - 1
+			r.insert(sectionKey.Line, column, r.resourceLines(section, recommended, missing, change))
+		}
+	}
+	return nil
+}
+
+// sectionLines returns the lines of a requests or limits mapping, relative to the given prefix
+func (r *rewriter) sectionLines(prefix, section string, recommended corev1.ResourceList, change Change) []string {
+	if len(recommended) == 0 {
+		return nil
+	}
+	lines := []string{prefix + section + ":"}
+	for _, line := range r.resourceLines(section, recommended, nil, change) {
+		lines = append(lines, prefix+indent+line)
+	}
+	return lines
+}
+
+// resourceLines returns the lines of the resources, all of them if only is nil
+func (r *rewriter) resourceLines(section string, recommended corev1.ResourceList, only []corev1.ResourceName, change Change) []string {
+	names := only
+	if names == nil {
+		names = sortedResourceNames(recommended)
+	}
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		quantity := recommended[name]
+		lines = append(lines, fmt.Sprintf("%s: %s", name, quantity.String()))
+		r.record(change, section+"."+string(name), "", quantity.String())
+	}
+	return lines
+}
+
+func (r *rewriter) record(change Change, field, from, to string) {
+	change.Field = field
+	change.From = from
+	change.To = to
+	r.changes = append(r.changes, change)
+}
+
+// insert queues the lines to be inserted after the line, indented to the column
+func (r *rewriter) insert(line, column int, lines []string) {
+	prefix := strings.Repeat(" ", column)
+	for _, l := range lines {
+		r.inserts[line] = append(r.inserts[line], prefix+l)
+	}
+}
+
+// replace queues the replacement of a scalar value, dropping any quotes
+func (r *rewriter) replace(node *yaml.Node, text string) {
+	length := len(node.Value)
+	if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		length += 2
+	}
+	r.replacements = append(r.replacements, replacement{
+		line:   node.Line,
+		column: node.Column,
+		length: length,
+		text:   text,
+	})
+}
+
+// clearEmpty removes an empty flow mapping ({}) or a null (null, ~) so that a block mapping can be inserted below it
+func (r *rewriter) clearEmpty(node *yaml.Node) {
+	var length int
+	switch {
+	case node.Kind == yaml.MappingNode:
+		length = len("{}")
+	case node.Kind == yaml.ScalarNode && node.Value != "":
+		length = len(node.Value)
+	default:
+		// an implicit null, e.g. "resources:", has no text to remove
+		return
+	}
+	r.replacements = append(r.replacements, replacement{
+		line:   node.Line,
+		column: node.Column,
+		length: length,
+	})
+}
+
+// apply applies all the queued replacements and inserts to the content
+func (r *rewriter) apply(content []byte) []byte {
+	lines := strings.Split(string(content), "\n")
+
+	sort.Slice(r.replacements, func(i, j int) bool {
+		if r.replacements[i].line != r.replacements[j].line {
+			return r.replacements[i].line > r.replacements[j].line
+		}
+		return r.replacements[i].column > r.replacements[j].column
+	})
+	for _, rep := range r.replacements {
+		line := lines[rep.line-1]
+		start := rep.column - 1
+		end := min(start+rep.length, len(line))
+		lines[rep.line-1] = strings.TrimRight(line[:start]+rep.text+line[end:], " ")
+	}
+
+	insertAfter := make([]int, 0, len(r.inserts))
+	for line := range r.inserts {
+		insertAfter = append(insertAfter, line)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(insertAfter)))
+	for _, line := range insertAfter {
+		inserted := append([]string{}, lines[:line]...)
+		inserted = append(inserted, r.inserts[line]...)
+		lines = append(inserted, lines[line:]...)
+	}
+
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// mappingEntry returns the key and value nodes of the key in the mapping
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	_, value := mappingEntry(node, key)
+	return value
+}
+
+func scalarValue(node *yaml.Node, key string) string {
+	value := mappingValue(node, key)
+	if value == nil || value.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return value.Value
+}
+
+// isEmpty returns true for null values and empty flow mappings
+func isEmpty(node *yaml.Node) bool {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return true
+	}
+	return node.Kind == yaml.MappingNode && len(node.Content) == 0
+}
+
+func sortedKeys(sections map[string]corev1.ResourceList) []string {
+	keys := make([]string, 0, len(sections))
+	for key, rl := range sections {
+		if len(rl) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	// requests before limits
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	return keys
+}
+
+func sortedResourceNames(rl corev1.ResourceList) []corev1.ResourceName {
+	names := make([]corev1.ResourceName, 0, len(rl))
+	for name := range rl {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] < names[j]
+	})
+	return names
+}
@@ -0,0 +1,316 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/fairwindsops/goldilocks/pkg/patch"
+)
+
+var recommended = corev1.ResourceRequirements{
+	Requests: corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("100m"),
+		corev1.ResourceMemory: resource.MustParse("128Mi"),
+	},
+	Limits: corev1.ResourceList{
+		corev1.ResourceMemory: resource.MustParse("256Mi"),
+	},
+}
+
+var testPatches = []patch.Patch{
+	{
+		Namespace: "testing",
+		Kind:      "Deployment",
+		Name:      "web",
+		Containers: []patch.Container{
+			{Name: "app", Resources: recommended},
+			{Name: "sidecar", Resources: recommended},
+			{Name: "init", Resources: recommended},
+		},
+	},
+	{
+		Namespace: "testing",
+		Kind:      "CronJob",
+		Name:      "nightly",
+		Containers: []patch.Container{
+			{Name: "job", Resources: recommended},
+		},
+	},
+}
+
+const testManifest = `# the web frontend
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: testing
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+        - name: app
+          image: nginx # pinned
+          resources:
+            requests:
+              cpu: "50m" # too small
+              memory: 128Mi
+            limits:
+              cpu: 1
+        - name: sidecar
+          image: envoy
+        - name: init
+          resources: {}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: nightly
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: job
+            resources:
+              limits:
+                memory: 1Gi
+`
+
+const expectedManifest = `# the web frontend
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: testing
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+        - name: app
+          image: nginx # pinned
+          resources:
+            requests:
+              cpu: 100m # too small
+              memory: 128Mi
+            limits:
+              memory: 256Mi
+              cpu: 1
+        - name: sidecar
+          resources:
+            requests:
+              cpu: 100m
+              memory: 128Mi
+            limits:
+              memory: 256Mi
+          image: envoy
+        - name: init
+          resources:
+            requests:
+              cpu: 100m
+              memory: 128Mi
+            limits:
+              memory: 256Mi
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: nightly
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: job
+            resources:
+              requests:
+                cpu: 100m
+                memory: 128Mi
+              limits:
+                memory: 256Mi
+`
+
+func TestRewrite(t *testing.T) {
+	got, changes, err := Rewrite([]byte(testManifest), testPatches)
+	require.NoError(t, err)
+	assert.Equal(t, expectedManifest, string(got))
+
+	// app: cpu request and memory limit, sidecar and init: 3 values each, job: 2 requests and a limit
+	assert.Len(t, changes, 11)
+	assert.Equal(t, Change{
+		Kind:      "Deployment",
+		Namespace: "testing",
+		Name:      "web",
+		Container: "app",
+		Field:     "requests.cpu",
+		From:      "50m",
+		To:        "100m",
+	}, changes[0])
+
+	// rewriting again is a no-op
+	again, changes, err := Rewrite(got, testPatches)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.Equal(t, string(got), string(again))
+}
+
+func TestRewrite_NoNamespace(t *testing.T) {
+	manifest := `kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+`
+	got, changes, err := Rewrite([]byte(manifest), testPatches)
+	require.NoError(t, err)
+	assert.Len(t, changes, 3)
+	assert.Contains(t, string(got), "      - name: app\n        resources:\n          requests:\n")
+
+	ambiguous := append([]patch.Patch{{Namespace: "other", Kind: "Deployment", Name: "web", Containers: testPatches[0].Containers}}, testPatches...)
+	_, changes, err = Rewrite([]byte(manifest), ambiguous)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestRewrite_InitContainers(t *testing.T) {
+	manifest := `kind: Deployment
+metadata:
+  name: web
+  namespace: testing
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: sidecar
+        restartPolicy: Always
+      containers:
+      - name: app
+`
+	got, changes, err := Rewrite([]byte(manifest), testPatches)
+	require.NoError(t, err)
+	assert.Len(t, changes, 6)
+	assert.Contains(t, string(got), "      - name: sidecar\n        resources:\n          requests:\n            cpu: 100m\n")
+}
+
+func TestRewrite_Null(t *testing.T) {
+	manifest := `kind: Deployment
+metadata:
+  name: web
+  namespace: testing
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources: ~
+      - name: sidecar
+        resources:
+          requests: null # set by the platform
+      - name: init
+        resources:
+`
+	expected := `kind: Deployment
+metadata:
+  name: web
+  namespace: testing
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          requests:
+            cpu: 100m
+            memory: 128Mi
+          limits:
+            memory: 256Mi
+      - name: sidecar
+        resources:
+          limits:
+            memory: 256Mi
+          requests:  # set by the platform
+            cpu: 100m
+            memory: 128Mi
+      - name: init
+        resources:
+          requests:
+            cpu: 100m
+            memory: 128Mi
+          limits:
+            memory: 256Mi
+`
+	got, changes, err := Rewrite([]byte(manifest), testPatches)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(got))
+	assert.Len(t, changes, 9)
+
+	// the result is valid yaml that rewrites to itself
+	again, changes, err := Rewrite(got, testPatches)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.Equal(t, string(got), string(again))
+}
+
+func TestApply(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "apps", "web.yaml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(testManifest), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("kind: Deployment"), 0644))
+
+	changes, err := Apply(dir, testPatches, true)
+	require.NoError(t, err)
+	assert.Len(t, changes, 11)
+	assert.Equal(t, path, changes[0].File)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, testManifest, string(content), "dry run does not write")
+
+	_, err = Apply(dir, testPatches, false)
+	require.NoError(t, err)
+	content, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, expectedManifest, string(content))
+}
@@ -0,0 +1,345 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"github.com/fairwindsops/goldilocks/pkg/summary"
+	"github.com/fairwindsops/goldilocks/pkg/utils"
+)
+
+// Format is the output format of the patches
+type Format string
+
+const (
+	// FormatStrategicMerge writes strategic merge patches for `kubectl patch --type strategic`
+	FormatStrategicMerge Format = "strategic-merge"
+	// FormatKustomize writes patch files that can be referenced from a kustomization
+	FormatKustomize Format = "kustomize"
+	// FormatKubectl writes `kubectl set resources` commands
+	FormatKubectl Format = "kubectl"
+)
+
+// Formats are all the supported formats
+var Formats = []Format{FormatStrategicMerge, FormatKustomize, FormatKubectl}
+
+// apiVersions of the workload kinds that goldilocks creates VPAs for
+var apiVersions = map[string]string{
+	"Deployment":  "apps/v1",
+	"StatefulSet": "apps/v1",
+	"DaemonSet":   "apps/v1",
+	"ReplicaSet":  "apps/v1",
+	"Job":         "batch/v1",
+	"CronJob":     "batch/v1",
+}
+
+// Patch is the recommended resources for the containers of a single workload
+type Patch struct {
+	Namespace  string
+	Kind       string
+	Name       string
+	Containers []Container
+}
+
+// Container is the recommended resources for a single container
+type Container struct {
+	Name string
+	// Init is true for native sidecars and init containers, which are patched in initContainers
+	Init      bool
+	Resources corev1.ResourceRequirements
+}
+
+// FromSummary builds a patch for every workload in the summary that has recommendations.
+// Containers whose requests are within the tolerance of the recommendation are skipped.
+// The recommended requests and limits of the summary are used when the summary has them,
+// otherwise they are derived from the recommendation with the strategy.
+func FromSummary(data summary.Summary, strategy utils.LimitStrategy) []Patch {
+	var patches []Patch
+	for _, ns := range data.Namespaces {
+		for _, workload := range ns.Workloads {
+			patch := Patch{
+				Namespace: ns.Namespace,
+				Kind:      workload.ControllerType,
+				Name:      workload.ControllerName,
+			}
+			for _, c := range workload.Containers {
+				if len(c.Target) == 0 {
+					continue
+				}
+				if c.IsWithinTolerance() {
+					klog.V(3).Infof("Skipping %s/%s/%s/%s, requests are within tolerance of the recommendation", ns.Namespace, workload.ControllerType, workload.ControllerName, c.ContainerName)
+					continue
+				}
+				patch.Containers = append(patch.Containers, Container{
+					Name:      c.ContainerName,
+					Init:      c.ContainerType == summary.ContainerTypeSidecar || c.ContainerType == summary.ContainerTypeInit,
+					Resources: resourcesForStrategy(c, strategy),
+				})
+			}
+			if len(patch.Containers) == 0 {
+				continue
+			}
+			sort.Slice(patch.Containers, func(i, j int) bool {
+				return patch.Containers[i].Name < patch.Containers[j].Name
+			})
+			patches = append(patches, patch)
+		}
+	}
+
+	sort.Slice(patches, func(i, j int) bool {
+		if patches[i].Namespace != patches[j].Namespace {
+			return patches[i].Namespace < patches[j].Namespace
+		}
+		if patches[i].Kind != patches[j].Kind {
+			return patches[i].Kind < patches[j].Kind
+		}
+		return patches[i].Name < patches[j].Name
+	})
+	return patches
+}
+
+// ForWorkload builds a patch of the containers of the workload, or of all its containers when none are given,
+// with the requests and limits derived from the recommendation with the strategy. Unlike FromSummary, the
+// recommended requests and limits of the summary are ignored, and containers within tolerance are patched.
+func ForWorkload(namespace string, workload summary.WorkloadSummary, strategy utils.LimitStrategy, containers ...string) (Patch, error) {
+	patch := Patch{
+		Namespace: namespace,
+		Kind:      workload.ControllerType,
+		Name:      workload.ControllerName,
+	}
+
+	names := containers
+	if len(names) == 0 {
+		for name := range workload.Containers {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		c, ok := workload.Containers[name]
+		if !ok || len(c.Target) == 0 {
+			return Patch{}, fmt.Errorf("no recommendation for container %s of %s %s/%s", name, workload.ControllerType, namespace, workload.ControllerName)
+		}
+		requests, limits := strategy.Recommend(c.Target, c.LowerBound, c.UpperBound)
+		patch.Containers = append(patch.Containers, Container{
+			Name:      c.ContainerName,
+			Init:      c.ContainerType == summary.ContainerTypeSidecar || c.ContainerType == summary.ContainerTypeInit,
+			Resources: corev1.ResourceRequirements{Requests: requests, Limits: limits},
+		})
+	}
+	if len(patch.Containers) == 0 {
+		return Patch{}, fmt.Errorf("no recommendations for %s %s/%s", workload.ControllerType, namespace, workload.ControllerName)
+	}
+	return patch, nil
+}
+
+// StrategicMerge returns the strategic merge patch of the workload as JSON, for the API server
+func (p Patch) StrategicMerge() ([]byte, error) {
+	return json.Marshal(p.body())
+}
+
+// Round applies the rounding policy to the resources of every patch, for patches
+// built from summaries that were not rounded when they were generated
+func Round(patches []Patch, rounding utils.RoundingPolicy) []Patch {
+	for i := range patches {
+		for j := range patches[i].Containers {
+			resources := &patches[i].Containers[j].Resources
+			resources.Requests = rounding.Apply(resources.Requests)
+			resources.Limits = rounding.Apply(resources.Limits)
+		}
+	}
+	return patches
+}
+
+func resourcesForStrategy(c summary.ContainerSummary, strategy utils.LimitStrategy) corev1.ResourceRequirements {
+	if len(c.RecommendedRequests) > 0 || len(c.RecommendedLimits) > 0 {
+		return corev1.ResourceRequirements{
+			Requests: c.RecommendedRequests,
+			Limits:   c.RecommendedLimits,
+		}
+	}
+	requests, limits := strategy.Recommend(c.Target, c.LowerBound, c.UpperBound)
+	return corev1.ResourceRequirements{
+		Requests: requests,
+		Limits:   limits,
+	}
+}
+
+// Write writes the patches to the writer in the given format
+func Write(w io.Writer, patches []Patch, format Format) error {
+	switch format {
+	case FormatStrategicMerge:
+		return writeStrategicMerge(w, patches)
+	case FormatKustomize:
+		return writeKustomize(w, patches)
+	case FormatKubectl:
+		return writeKubectl(w, patches)
+	}
+	return fmt.Errorf("unknown format %q, must be one of %v", format, Formats)
+}
+
+func writeStrategicMerge(w io.Writer, patches []Patch) error {
+	for i, p := range patches {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w, "---"); err != nil {
+				return err
+			}
+		}
+		body, err := yaml.Marshal(p.body())
+		if err != nil {
+			return err
+		}
+		// kubectl patch takes a single patch, so the command has the patch of its own document inline
+		inline, err := p.StrategicMerge()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "# kubectl patch %s %s -n %s --type strategic -p '%s'\n%s", strings.ToLower(p.Kind), p.Name, p.Namespace, inline, body)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeKustomize(w io.Writer, patches []Patch) error {
+	for i, p := range patches {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w, "---"); err != nil {
+				return err
+			}
+		}
+		document := p.body()
+		document["apiVersion"] = apiVersions[p.Kind]
+		document["kind"] = p.Kind
+		document["metadata"] = map[string]any{
+			"name":      p.Name,
+			"namespace": p.Namespace,
+		}
+		body, err := yaml.Marshal(document)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeKubectl(w io.Writer, patches []Patch) error {
+	for _, p := range patches {
+		for _, c := range p.Containers {
+			args := []string{
+				"kubectl", "set", "resources",
+				fmt.Sprintf("%s/%s", strings.ToLower(p.Kind), p.Name),
+				"-n", p.Namespace,
+				"-c", c.Name,
+			}
+			if requests := formatResourceList(c.Resources.Requests); requests != "" {
+				args = append(args, "--requests="+requests)
+			}
+			if limits := formatResourceList(c.Resources.Limits); limits != "" {
+				args = append(args, "--limits="+limits)
+			}
+			if _, err := fmt.Fprintln(w, strings.Join(args, " ")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// body returns the spec of a strategic merge patch setting the container resources
+func (p Patch) body() map[string]any {
+	var containers, initContainers []any
+	for _, c := range p.Containers {
+		resources := map[string]any{}
+		if len(c.Resources.Requests) > 0 {
+			resources["requests"] = resourceListToMap(c.Resources.Requests)
+		}
+		if len(c.Resources.Limits) > 0 {
+			resources["limits"] = resourceListToMap(c.Resources.Limits)
+		}
+		container := map[string]any{
+			"name":      c.Name,
+			"resources": resources,
+		}
+		if c.Init {
+			initContainers = append(initContainers, container)
+		} else {
+			containers = append(containers, container)
+		}
+	}
+
+	spec := map[string]any{}
+	if len(containers) > 0 {
+		spec["containers"] = containers
+	}
+	if len(initContainers) > 0 {
+		spec["initContainers"] = initContainers
+	}
+	podSpec := map[string]any{
+		"template": map[string]any{
+			"spec": spec,
+		},
+	}
+	if p.Kind == "CronJob" {
+		return map[string]any{
+			"spec": map[string]any{
+				"jobTemplate": map[string]any{
+					"spec": podSpec,
+				},
+			},
+		}
+	}
+	return map[string]any{
+		"spec": podSpec,
+	}
+}
+
+func resourceListToMap(rl corev1.ResourceList) map[string]any {
+	values := map[string]any{}
+	for name, quantity := range rl {
+		values[string(name)] = quantity.String()
+	}
+	return values
+}
+
+// formatResourceList formats the resource list as cpu=100m,memory=128Mi
+func formatResourceList(rl corev1.ResourceList) string {
+	names := make([]string, 0, len(rl))
+	for name := range rl {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	items := make([]string, 0, len(names))
+	for _, name := range names {
+		quantity := rl[corev1.ResourceName(name)]
+		items = append(items, fmt.Sprintf("%s=%s", name, quantity.String()))
+	}
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,229 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/fairwindsops/goldilocks/pkg/summary"
+	"github.com/fairwindsops/goldilocks/pkg/utils"
+)
+
+const testSummaryJSON = `{
+  "apiVersion": "goldilocks.fairwinds.com/v1",
+  "namespaces": {
+    "testing": {
+      "namespace": "testing",
+      "workloads": {
+        "web": {
+          "controllerName": "web",
+          "controllerType": "Deployment",
+          "containers": {
+            "app": {
+              "containerName": "app",
+              "lowerBound": {"cpu": "10m", "memory": "10Mi"},
+              "upperBound": {"cpu": "500m", "memory": "500Mi"},
+              "target": {"cpu": "100m", "memory": "100Mi"},
+              "requests": {"cpu": "50m", "memory": "64Mi"},
+              "requestStatus": {"cpu": "less than", "memory": "less than"}
+            },
+            "sidecar": {
+              "containerName": "sidecar",
+              "target": {"cpu": "20m", "memory": "32Mi"},
+              "requests": {"cpu": "20m", "memory": "32Mi"},
+              "requestStatus": {"cpu": "equal", "memory": "equal"}
+            }
+          }
+        },
+        "nightly": {
+          "controllerName": "nightly",
+          "controllerType": "CronJob",
+          "containers": {
+            "job": {
+              "containerName": "job",
+              "target": {"cpu": "1", "memory": "1Gi"}
+            }
+          }
+        },
+        "empty": {
+          "controllerName": "empty",
+          "controllerType": "Deployment",
+          "containers": {}
+        }
+      }
+    }
+  }
+}`
+
+func testPatches(t *testing.T, strategy string) []Patch {
+	var data summary.Summary
+	require.NoError(t, json.Unmarshal([]byte(testSummaryJSON), &data))
+	limitStrategy, err := utils.ParseLimitStrategy(strategy)
+	require.NoError(t, err)
+	return FromSummary(data, limitStrategy)
+}
+
+func TestFromSummary(t *testing.T) {
+	patches := testPatches(t, utils.LimitStrategyGuaranteed)
+	require.Len(t, patches, 2)
+
+	assert.Equal(t, "CronJob", patches[0].Kind)
+	assert.Equal(t, "Deployment", patches[1].Kind)
+
+	// the sidecar is within tolerance and is skipped
+	require.Len(t, patches[1].Containers, 1)
+	assert.Equal(t, "app", patches[1].Containers[0].Name)
+	assert.Equal(t, "100m", patches[1].Containers[0].Resources.Limits.Cpu().String())
+
+	burstable := testPatches(t, utils.LimitStrategyBurstable)
+	assert.Equal(t, "10m", burstable[1].Containers[0].Resources.Requests.Cpu().String())
+	assert.Equal(t, "500m", burstable[1].Containers[0].Resources.Limits.Cpu().String())
+
+	noCPULimit := testPatches(t, "no-cpu-limit,ratio:1.5")
+	assert.Equal(t, "100m", noCPULimit[1].Containers[0].Resources.Requests.Cpu().String())
+	assert.Equal(t, "150Mi", noCPULimit[1].Containers[0].Resources.Limits.Memory().String())
+	assert.NotContains(t, noCPULimit[1].Containers[0].Resources.Limits, corev1.ResourceCPU)
+
+	// the recommended requests and limits of the summary take precedence over the strategy
+	var data summary.Summary
+	require.NoError(t, json.Unmarshal([]byte(testSummaryJSON), &data))
+	web := data.Namespaces["testing"].Workloads["web"]
+	app := web.Containers["app"]
+	app.RecommendedRequests = app.Target
+	app.RecommendedLimits = corev1.ResourceList{corev1.ResourceMemory: app.UpperBound[corev1.ResourceMemory]}
+	web.Containers["app"] = app
+	data.Namespaces["testing"].Workloads["web"] = web
+	fromSummary := FromSummary(data, utils.LimitStrategy{Burstable: true})
+	assert.Equal(t, "100m", fromSummary[1].Containers[0].Resources.Requests.Cpu().String())
+	assert.Equal(t, corev1.ResourceList{corev1.ResourceMemory: app.UpperBound[corev1.ResourceMemory]}, fromSummary[1].Containers[0].Resources.Limits)
+}
+
+func TestForWorkload(t *testing.T) {
+	var data summary.Summary
+	require.NoError(t, json.Unmarshal([]byte(testSummaryJSON), &data))
+	web := data.Namespaces["testing"].Workloads["web"]
+
+	// every container, including the sidecar within tolerance
+	guaranteed, err := ForWorkload("testing", web, utils.LimitStrategy{Ratio: 1})
+	require.NoError(t, err)
+	require.Len(t, guaranteed.Containers, 2)
+	assert.Equal(t, "app", guaranteed.Containers[0].Name)
+	assert.Equal(t, "100m", guaranteed.Containers[0].Resources.Limits.Cpu().String())
+
+	burstable, err := ForWorkload("testing", web, utils.LimitStrategy{Burstable: true}, "app")
+	require.NoError(t, err)
+	require.Len(t, burstable.Containers, 1)
+	assert.Equal(t, "10m", burstable.Containers[0].Resources.Requests.Cpu().String())
+	body, err := burstable.StrategicMerge()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"spec":{"template":{"spec":{"containers":[{"name":"app","resources":{"requests":{"cpu":"10m","memory":"10Mi"},"limits":{"cpu":"500m","memory":"500Mi"}}}]}}}}`, string(body))
+
+	_, err = ForWorkload("testing", web, utils.LimitStrategy{}, "missing")
+	assert.Error(t, err)
+	_, err = ForWorkload("testing", data.Namespaces["testing"].Workloads["empty"], utils.LimitStrategy{})
+	assert.Error(t, err)
+}
+
+func TestRound(t *testing.T) {
+	rounding, err := utils.NewRoundingPolicy("250m", "", "up", "", "")
+	require.NoError(t, err)
+
+	patches := Round(testPatches(t, utils.LimitStrategyBurstable), rounding)
+	assert.Equal(t, "250m", patches[1].Containers[0].Resources.Requests.Cpu().String())
+	assert.Equal(t, "500m", patches[1].Containers[0].Resources.Limits.Cpu().String())
+}
+
+func TestWrite(t *testing.T) {
+	patches := testPatches(t, utils.LimitStrategyGuaranteed)
+
+	var kubectl bytes.Buffer
+	require.NoError(t, Write(&kubectl, patches, FormatKubectl))
+	assert.Equal(t, `kubectl set resources cronjob/nightly -n testing -c job --requests=cpu=1,memory=1Gi --limits=cpu=1,memory=1Gi
+kubectl set resources deployment/web -n testing -c app --requests=cpu=100m,memory=100Mi --limits=cpu=100m,memory=100Mi
+`, kubectl.String())
+
+	var kustomize bytes.Buffer
+	require.NoError(t, Write(&kustomize, patches[1:], FormatKustomize))
+	assert.Equal(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: testing
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          limits:
+            cpu: 100m
+            memory: 100Mi
+          requests:
+            cpu: 100m
+            memory: 100Mi
+`, kustomize.String())
+
+	var strategic bytes.Buffer
+	require.NoError(t, Write(&strategic, patches[:1], FormatStrategicMerge))
+	assert.Contains(t, strategic.String(), "# kubectl patch cronjob nightly -n testing --type strategic -p '{\"spec\":{\"jobTemplate\":")
+	assert.Contains(t, strategic.String(), "jobTemplate:")
+
+	// every document of multi-document output has its own patch in the command
+	strategic.Reset()
+	require.NoError(t, Write(&strategic, patches, FormatStrategicMerge))
+	documents := strings.Split(strategic.String(), "---\n")
+	require.Len(t, documents, len(patches))
+	for i, document := range documents {
+		inline, err := patches[i].StrategicMerge()
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(document, fmt.Sprintf("# kubectl patch %s %s -n %s --type strategic -p '%s'\n", strings.ToLower(patches[i].Kind), patches[i].Name, patches[i].Namespace, inline)), document)
+		assert.NotContains(t, document, "patch-file")
+	}
+
+	assert.Error(t, Write(&strategic, patches, Format("bogus")))
+}
+
+func TestWrite_InitContainers(t *testing.T) {
+	resources := corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m")}}
+	patches := []Patch{{
+		Namespace: "testing",
+		Kind:      "Deployment",
+		Name:      "web",
+		Containers: []Container{
+			{Name: "app", Resources: resources},
+			{Name: "proxy", Init: true, Resources: resources},
+		},
+	}}
+
+	var kustomize bytes.Buffer
+	require.NoError(t, Write(&kustomize, patches, FormatKustomize))
+	assert.Contains(t, kustomize.String(), `      containers:
+      - name: app
+        resources:
+          requests:
+            cpu: 10m
+      initContainers:
+      - name: proxy
+`)
+}
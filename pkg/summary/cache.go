@@ -0,0 +1,377 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	controllerUtils "github.com/fairwindsops/controller-utils/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	vpainformers "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/client/informers/externalversions"
+	vpalisters "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/client/listers/autoscaling.k8s.io/v1"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/fairwindsops/goldilocks/pkg/kube"
+)
+
+// podResource is the resource of the pods whose top controllers are the workloads
+var podResource = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}
+
+// workloadResources are the resources the workloads are built from, next to the pods
+var workloadResources = []schema.GroupVersionResource{
+	{Group: "apps", Version: "v1", Resource: "deployments"},
+	{Group: "apps", Version: "v1", Resource: "statefulsets"},
+	{Group: "apps", Version: "v1", Resource: "daemonsets"},
+	{Group: "apps", Version: "v1", Resource: "replicasets"},
+	{Group: "batch", Version: "v1", Resource: "jobs"},
+	{Group: "batch", Version: "v1", Resource: "cronjobs"},
+}
+
+// Cache keeps what summaries are made of in memory, so that summaries don't list the VPAs and
+// workloads of the cluster every time. VPAs, namespaces and nodes are served from informers.
+// Workloads are built again from the informers of pods and workloads in the background when
+// they see a change, so they are at most the max staleness behind the cluster.
+type Cache struct {
+	maxStaleness          time.Duration
+	controllerUtilsClient *kube.ControllerUtilsClientInstance
+
+	kubeFactory    informers.SharedInformerFactory
+	vpaFactory     vpainformers.SharedInformerFactory
+	dynamicFactory dynamicinformer.DynamicSharedInformerFactory
+
+	vpas       vpalisters.VerticalPodAutoscalerLister
+	namespaces corelisters.NamespaceLister
+	nodes      corelisters.NodeLister
+	pods       cache.GenericLister
+	// controllers are the listers of the workload resources
+	controllers []cache.GenericLister
+	hasSynced   []cache.InformerSynced
+
+	// changed is set by the workload informers, and cleared when the workloads are built
+	changed atomic.Bool
+
+	mu        sync.RWMutex
+	synced    bool
+	workloads []controllerUtils.Workload
+	// current is when the workloads were last known to match the cluster
+	current time.Time
+	err     error
+}
+
+// CacheStatus is the sync status of a Cache
+type CacheStatus struct {
+	// Synced is whether the informers have synced and the workloads have been built
+	Synced bool `json:"synced"`
+	// Stale is whether the workloads are behind the cluster by more than the max staleness
+	Stale bool `json:"stale"`
+	// LastSync is when the workloads were last known to match the cluster
+	LastSync time.Time `json:"lastSync,omitzero"`
+	// Error is the error of the last build of the workloads
+	Error string `json:"error,omitempty"`
+}
+
+// Healthy is whether summaries from the cache are synced and not stale
+func (s CacheStatus) Healthy() bool {
+	return s.Synced && !s.Stale
+}
+
+// NewCache returns a Cache of the summaries of all namespaces, whose workloads are at most maxStaleness
+// behind the cluster. The cache is empty until it is run.
+func NewCache(maxStaleness time.Duration) *Cache {
+	c := &Cache{
+		maxStaleness:          maxStaleness,
+		controllerUtilsClient: kube.GetControllerUtilsInstance(),
+		kubeFactory:           informers.NewSharedInformerFactory(kube.GetInstance().Client, 0),
+		vpaFactory:            vpainformers.NewSharedInformerFactory(kube.GetVPAInstance().Client, 0),
+		dynamicFactory:        dynamicinformer.NewDynamicSharedInformerFactory(kube.GetDynamicInstance().Client, 0),
+	}
+
+	vpaInformer := c.vpaFactory.Autoscaling().V1().VerticalPodAutoscalers()
+	namespaceInformer := c.kubeFactory.Core().V1().Namespaces()
+	nodeInformer := c.kubeFactory.Core().V1().Nodes()
+	c.vpas = vpaInformer.Lister()
+	c.namespaces = namespaceInformer.Lister()
+	c.nodes = nodeInformer.Lister()
+	c.hasSynced = append(c.hasSynced, vpaInformer.Informer().HasSynced, namespaceInformer.Informer().HasSynced, nodeInformer.Informer().HasSynced)
+
+	// VPAs, namespaces and nodes are read from the informers, only changed workloads need to be built again
+	onChange := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(any) { c.changed.Store(true) },
+		UpdateFunc: func(any, any) { c.changed.Store(true) },
+		DeleteFunc: func(any) { c.changed.Store(true) },
+	}
+	c.pods = c.watch(podResource, onChange)
+	for _, resource := range workloadResources {
+		c.controllers = append(c.controllers, c.watch(resource, onChange))
+	}
+	return c
+}
+
+// watch returns the lister of the resource, whose informer calls the handler
+func (c *Cache) watch(resource schema.GroupVersionResource, handler cache.ResourceEventHandler) cache.GenericLister {
+	informer := c.dynamicFactory.ForResource(resource)
+	if _, err := informer.Informer().AddEventHandler(handler); err != nil {
+		klog.Errorf("Error watching %s: %v", resource.Resource, err)
+	}
+	c.hasSynced = append(c.hasSynced, informer.Informer().HasSynced)
+	return informer.Lister()
+}
+
+// Run starts the informers and keeps the workloads up to date until the context is done
+func (c *Cache) Run(ctx context.Context) error {
+	c.kubeFactory.Start(ctx.Done())
+	c.vpaFactory.Start(ctx.Done())
+	c.dynamicFactory.Start(ctx.Done())
+	defer c.kubeFactory.Shutdown()
+	defer c.vpaFactory.Shutdown()
+	defer c.dynamicFactory.Shutdown()
+
+	klog.Info("Waiting for the summary cache to sync")
+	if !cache.WaitForCacheSync(ctx.Done(), c.hasSynced...) {
+		return fmt.Errorf("timed out waiting for the summary cache to sync: %w", ctx.Err())
+	}
+	c.changed.Store(true)
+	c.refresh()
+	klog.Info("Summary cache synced")
+
+	// check for changes twice per max staleness, so a change is built within the max staleness
+	ticker := time.NewTicker(max(c.maxStaleness/2, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			c.refresh()
+		}
+	}
+}
+
+// refresh builds the workloads again if they changed since they were last built
+func (c *Cache) refresh() {
+	now := time.Now()
+	if !c.changed.Swap(false) {
+		// failed builds are retried, so nothing changed since the last successful build
+		c.mu.Lock()
+		c.current = now
+		c.mu.Unlock()
+		return
+	}
+
+	klog.V(3).Info("Building the workloads of the summary cache")
+	workloads, err := c.buildWorkloads()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+	if err != nil {
+		klog.Errorf("Error building the workloads of the summary cache: %v", err)
+		// try again with the next check
+		c.changed.Store(true)
+		return
+	}
+	c.workloads = workloads
+	c.current = now
+	c.synced = true
+}
+
+// buildWorkloads returns the top controllers of the pods from the informers, like GetAllTopControllersSummary
+// of controller-utils does from listings. Only owners of kinds without an informer are listed.
+func (c *Cache) buildWorkloads() ([]controllerUtils.Workload, error) {
+	// the objects of the informers must not be modified, and the workloads aren't
+	objectCache := map[string]unstructured.Unstructured{}
+	workloads := map[string]*controllerUtils.Workload{}
+	for _, lister := range c.controllers {
+		objects, err := lister.List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			controller, ok := object.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			key := controllerKey(*controller)
+			objectCache[key] = *controller
+			if len(controller.GetOwnerReferences()) > 0 {
+				continue
+			}
+			podMetadata, podSpec, err := controllerUtils.GetPodMetadataAndSpec(controller.UnstructuredContent())
+			if err != nil {
+				return nil, err
+			}
+			workloads[key] = &controllerUtils.Workload{TopController: *controller, PodSpec: podSpec, PodMetadata: podMetadata}
+		}
+	}
+
+	pods, err := c.pods.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range pods {
+		pod, ok := object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		controller, err := c.controllerUtilsClient.Client.GetTopController(*pod, objectCache)
+		if err != nil {
+			// like controller-utils, the pod is summarized with the highest owner that was found
+			klog.V(2).Infof("unable to get the top controller of pod %s/%s: %v", pod.GetNamespace(), pod.GetName(), err)
+		}
+		key := controllerKey(controller)
+		workload, ok := workloads[key]
+		if !ok {
+			workload = &controllerUtils.Workload{TopController: controller}
+			workload.PodMetadata, workload.PodSpec, err = controllerUtils.GetPodMetadataAndSpec(controller.UnstructuredContent())
+			if err != nil {
+				return nil, err
+			}
+			if workload.PodSpec == nil {
+				workload.PodMetadata, workload.PodSpec, err = controllerUtils.GetPodMetadataAndSpec(pod.UnstructuredContent())
+				if err != nil {
+					return nil, err
+				}
+			}
+			workloads[key] = workload
+		}
+		workload.PodCount++
+		if phase, _, _ := unstructured.NestedString(pod.Object, "status", "phase"); phase == string(corev1.PodRunning) {
+			workload.RunningPodCount++
+		}
+	}
+
+	result := make([]controllerUtils.Workload, 0, len(workloads))
+	for _, workload := range workloads {
+		result = append(result, *workload)
+	}
+	return result, nil
+}
+
+// controllerKey is the key of the object in the object cache of controller-utils
+func controllerKey(object unstructured.Unstructured) string {
+	return fmt.Sprintf("%s/%s/%s", object.GetKind(), object.GetNamespace(), object.GetName())
+}
+
+// Synced is whether the informers have synced and the workloads have been built. A nil Cache is never synced.
+func (c *Cache) Synced() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.synced
+}
+
+// Healthy is whether the cache is synced and not stale, so that summaries can be made from it instead of
+// listings of the cluster. A nil Cache is never healthy.
+func (c *Cache) Healthy() bool {
+	return c != nil && c.Status().Healthy()
+}
+
+// Status returns the sync status of the cache
+func (c *Cache) Status() CacheStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	status := CacheStatus{
+		Synced:   c.synced,
+		LastSync: c.current,
+		Stale:    c.synced && time.Since(c.current) > c.maxStaleness,
+	}
+	if c.err != nil {
+		status.Error = c.err.Error()
+	}
+	return status
+}
+
+// Namespaces returns the cached namespaces matching the selector
+func (c *Cache) Namespaces(selector labels.Selector) ([]*corev1.Namespace, error) {
+	return c.namespaces.List(selector)
+}
+
+// listVPAs returns the cached VPAs of the namespace with the labels, of all namespaces for namespaceAllNamespaces
+func (c *Cache) listVPAs(namespace string, vpaLabels map[string]string) ([]vpav1.VerticalPodAutoscaler, error) {
+	selector := labels.SelectorFromSet(vpaLabels)
+	var cached []*vpav1.VerticalPodAutoscaler
+	var err error
+	if namespace == namespaceAllNamespaces {
+		cached, err = c.vpas.List(selector)
+	} else {
+		cached, err = c.vpas.VerticalPodAutoscalers(namespace).List(selector)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vpas := make([]vpav1.VerticalPodAutoscaler, 0, len(cached))
+	for _, vpa := range cached {
+		vpas = append(vpas, *vpa.DeepCopy())
+	}
+	return vpas, nil
+}
+
+// listWorkloads returns the cached workloads of the namespace, of all namespaces for namespaceAllNamespaces.
+// The workloads are shared with other summaries and must not be modified.
+func (c *Cache) listWorkloads(namespace string) []controllerUtils.Workload {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if namespace == namespaceAllNamespaces {
+		return c.workloads
+	}
+
+	var workloads []controllerUtils.Workload
+	for _, workload := range c.workloads {
+		if workload.TopController.GetNamespace() == namespace {
+			workloads = append(workloads, workload)
+		}
+	}
+	return workloads
+}
+
+// getNamespace returns the cached namespace, false if it doesn't exist
+func (c *Cache) getNamespace(name string) (*corev1.Namespace, bool) {
+	ns, err := c.namespaces.Get(name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			klog.V(2).Infof("unable to get namespace %s from the cache: %v", name, err)
+		}
+		return nil, false
+	}
+	return ns, true
+}
+
+// getNode returns the cached node, false if it doesn't exist
+func (c *Cache) getNode(name string) (*corev1.Node, bool) {
+	node, err := c.nodes.Get(name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			klog.V(2).Infof("unable to get node %s from the cache: %v", name, err)
+		}
+		return nil, false
+	}
+	return node, true
+}
@@ -0,0 +1,192 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	core "k8s.io/client-go/testing"
+
+	"github.com/fairwindsops/goldilocks/pkg/kube"
+)
+
+func TestCache(t *testing.T) {
+	kubeClientVPA := kube.GetMockVPAClient()
+	kube.GetMockClient()
+	dynamicClient := kube.GetMockDynamicClient()
+	kube.GetMockControllerUtilsClient(dynamicClient)
+
+	deployments := dynamicClient.Client.Resource(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}).Namespace("testing")
+	replicaSets := dynamicClient.Client.Resource(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}).Namespace("testing")
+	pods := dynamicClient.Client.Resource(schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}).Namespace("testing")
+	vpas := kubeClientVPA.Client.AutoscalingV1().VerticalPodAutoscalers("testing")
+
+	_, err := deployments.Create(context.TODO(), testDeploymentBasicUnstructured, metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = replicaSets.Create(context.TODO(), testDeploymentBasicReplicaSetUnstructured, metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = pods.Create(context.TODO(), testDeploymentBasicPodUnstructured, metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = vpas.Create(context.TODO(), testVPABasic, metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = vpas.Create(context.TODO(), testVPANoLabels, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	c := NewCache(time.Hour)
+	assert.False(t, c.Synced())
+	assert.False(t, c.Healthy())
+	assert.False(t, (*Cache)(nil).Healthy())
+	assert.False(t, c.Status().Healthy())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		assert.NoError(t, c.Run(ctx))
+	}()
+	require.Eventually(t, c.Synced, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, c.Status().Healthy())
+	assert.True(t, c.Healthy())
+	assert.False(t, c.changed.Load())
+
+	// a new VPA is summarized from the informer, a new workload once the workloads are listed again
+	_, err = vpas.Create(context.TODO(), testVPAWithReco, metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		vpas, err := c.listVPAs("testing", nil)
+		return err == nil && len(vpas) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err = deployments.Create(context.TODO(), testDeploymentWithRecoUnstructured, metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = replicaSets.Create(context.TODO(), testDeploymentWithRecoReplicaSetUnstructured, metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = pods.Create(context.TODO(), testDeploymentWithRecoPodUnstructured, metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, c.changed.Load, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, c.listWorkloads("testing"), 1)
+	// the workloads are built from the informers, without listing from the API
+	fakeClient := dynamicClient.Client.(*dynamicfake.FakeDynamicClient)
+	fakeClient.ClearActions()
+	c.refresh()
+	assert.Len(t, c.listWorkloads("testing"), 2)
+	for _, action := range fakeClient.Actions() {
+		assert.NotEqual(t, "list", action.GetVerb(), action.GetResource().Resource)
+	}
+	assert.Empty(t, c.listWorkloads("other"))
+
+	got, err := NewSummarizer(FromCache(c)).GetSummary()
+	assert.NoError(t, err)
+	assert.EqualValues(t, testSummary, withoutTotals(got))
+
+	// the workloads are stale when they weren't listed within the max staleness
+	c.mu.Lock()
+	c.current = time.Now().Add(-2 * time.Hour)
+	c.mu.Unlock()
+	assert.True(t, c.Status().Stale)
+	assert.False(t, c.Healthy())
+
+	// summaries list from the cluster instead of the stale workloads
+	fakeClient.ClearActions()
+	got, err = NewSummarizer(FromCache(c)).GetSummary()
+	assert.NoError(t, err)
+	assert.EqualValues(t, testSummary, withoutTotals(got))
+	assert.True(t, slices.ContainsFunc(fakeClient.Actions(), func(action core.Action) bool {
+		return action.GetVerb() == "list"
+	}))
+
+	c.refresh()
+	assert.False(t, c.Status().Stale)
+}
+
+func TestCacheConcurrentSummaries(t *testing.T) {
+	kubeClientVPA := kube.GetMockVPAClient()
+	kube.GetMockClient()
+	dynamicClient := kube.GetMockDynamicClient()
+	kube.GetMockControllerUtilsClient(dynamicClient)
+
+	// the pod spec of CronJobs isn't at spec.template.spec, so it's read from the shared workload
+	cronJob := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "batch/v1",
+		"kind":       "CronJob",
+		"metadata":   map[string]any{"name": "backup", "namespace": "testing"},
+		"spec": map[string]any{"jobTemplate": map[string]any{"spec": map[string]any{"template": map[string]any{"spec": map[string]any{
+			"containers": []any{map[string]any{"name": "container", "resources": map[string]any{
+				"limits":   map[string]any{"memory": "100Mi"},
+				"requests": map[string]any{"memory": "100Mi"},
+			}}},
+		}}}}},
+	}}
+	job := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata": map[string]any{"name": "backup-1", "namespace": "testing", "ownerReferences": []any{
+			map[string]any{"apiVersion": "batch/v1", "kind": "CronJob", "controller": true, "name": "backup"},
+		}},
+		"spec": map[string]any{},
+	}}
+	pod := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]any{"name": "backup-1-abcde", "namespace": "testing", "ownerReferences": []any{
+			map[string]any{"apiVersion": "batch/v1", "kind": "Job", "controller": true, "name": "backup-1"},
+		}},
+		"spec": map[string]any{},
+	}}
+	vpa := testVPAWithReco.DeepCopy()
+	vpa.Name = "goldilocks-backup"
+	vpa.Spec.TargetRef.APIVersion, vpa.Spec.TargetRef.Kind, vpa.Spec.TargetRef.Name = "batch/v1", "CronJob", "backup"
+
+	for resource, object := range map[string]*unstructured.Unstructured{"cronjobs": cronJob, "jobs": job, "pods": pod} {
+		gvr := schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: resource}
+		if resource == "pods" {
+			gvr.Group = ""
+		}
+		_, err := dynamicClient.Client.Resource(gvr).Namespace("testing").Create(context.TODO(), object, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+	_, err := kubeClientVPA.Client.AutoscalingV1().VerticalPodAutoscalers("testing").Create(context.TODO(), vpa, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	c := NewCache(time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		assert.NoError(t, c.Run(ctx))
+	}()
+	require.Eventually(t, c.Synced, 5*time.Second, 10*time.Millisecond)
+
+	// summaries must not modify the shared workloads, run with -race
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := NewSummarizer(FromCache(c)).GetSummary()
+			if assert.NoError(t, err) {
+				assert.Contains(t, got.Namespaces["testing"].Workloads, "batch/v1/CronJob/backup")
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -47,6 +47,12 @@ var targetResources = v1.ResourceList{
 	v1.ResourceMemory: resource.MustParse("100Mi"),
 }
 
+// equalStatus is the status of containers whose requests and limits match the target
+var equalStatus = map[v1.ResourceName]ResourceStatus{
+	v1.ResourceCPU:    StatusEqual,
+	v1.ResourceMemory: StatusEqual,
+}
+
 // Basic VPA and Deployment
 
 var testVPABasic = &vpav1.VerticalPodAutoscaler{
@@ -252,33 +258,52 @@ var testDeploymentWithRecoPodUnstructured = &unstructured.Unstructured{
 // The summary of these objects
 
 var testSummary = Summary{
-	Namespaces: map[string]namespaceSummary{
+	APIVersion: APIVersion,
+	Namespaces: map[string]NamespaceSummary{
 		"testing": {
 			Namespace:       "testing",
 			IsOnlyNamespace: true,
-			Workloads: map[string]workloadSummary{
-				"test-basic": {
+			Workloads: map[string]WorkloadSummary{
+				"apps/v1/Deployment/test-basic": {
+					APIVersion:     "apps/v1",
 					ControllerName: "test-basic",
 					ControllerType: "Deployment",
 					Containers:     map[string]ContainerSummary{},
+					Status:         WorkloadStatusNoRecommendationYet,
 				},
-				"test-vpa-with-reco": {
+				"apps/v1/Deployment/test-vpa-with-reco": {
+					APIVersion:     "apps/v1",
 					ControllerName: "test-vpa-with-reco",
 					ControllerType: "Deployment",
+					Status:         WorkloadStatusOK,
+					Replicas:       1,
 					Containers: map[string]ContainerSummary{
 						"container": {
 							ContainerName: "container",
+							ContainerType: ContainerTypeContainer,
 							LowerBound:    lowerBound,
 							UpperBound:    upperBound,
 							Target:        targetResources,
 							Limits:        targetResources,
 							Requests:      targetResources,
+							RequestStatus: equalStatus,
+							LimitStatus:   equalStatus,
 						},
 					},
 				},
 			},
 		},
 	},
+	Warnings: []Warning{
+		{
+			Type:      WorkloadStatusNoRecommendationYet,
+			Namespace: "testing",
+			VPA:       "goldilocks-test-basic",
+			Kind:      "Deployment",
+			Workload:  "test-basic",
+			Message:   "VPA goldilocks-test-basic has no recommendations yet",
+		},
+	},
 }
 
 // DaemonSet test and VPA
@@ -368,22 +393,29 @@ var testDaemonSetVPAWithReco = &vpav1.VerticalPodAutoscaler{
 // The summary of the daemonset
 
 var testSummaryDaemonSet = Summary{
-	Namespaces: map[string]namespaceSummary{
+	APIVersion: APIVersion,
+	Namespaces: map[string]NamespaceSummary{
 		"testing-daemonset": {
 			Namespace:       "testing-daemonset",
 			IsOnlyNamespace: true,
-			Workloads: map[string]workloadSummary{
-				"test-ds-with-reco": {
+			Workloads: map[string]WorkloadSummary{
+				"apps/v1/DaemonSet/test-ds-with-reco": {
+					APIVersion:     "apps/v1",
 					ControllerName: "test-ds-with-reco",
 					ControllerType: "DaemonSet",
+					Status:         WorkloadStatusOK,
+					Replicas:       1,
 					Containers: map[string]ContainerSummary{
 						"container": {
 							ContainerName: "container",
+							ContainerType: ContainerTypeContainer,
 							LowerBound:    lowerBound,
 							UpperBound:    upperBound,
 							Target:        targetResources,
 							Limits:        targetResources,
 							Requests:      targetResources,
+							RequestStatus: equalStatus,
+							LimitStatus:   equalStatus,
 						},
 					},
 				},
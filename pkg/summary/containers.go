@@ -0,0 +1,63 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ContainerType is where a container is defined in the pod spec
+type ContainerType string
+
+const (
+	// ContainerTypeContainer is a regular container
+	ContainerTypeContainer ContainerType = "container"
+	// ContainerTypeSidecar is a native sidecar, a restartable init container
+	ContainerTypeSidecar ContainerType = "sidecar"
+	// ContainerTypeInit is an init container that runs to completion before the other containers start
+	ContainerTypeInit ContainerType = "init"
+)
+
+// Label is the name of the container type shown on the dashboard
+func (t ContainerType) Label() string {
+	switch t {
+	case ContainerTypeSidecar:
+		return "Sidecar"
+	case ContainerTypeInit:
+		return "Init Container"
+	}
+	return "Container"
+}
+
+type podContainer struct {
+	corev1.Container
+	containerType ContainerType
+}
+
+// podContainers returns the containers, native sidecars and init containers of the pod spec
+func podContainers(podSpec corev1.PodSpec) []podContainer {
+	containers := make([]podContainer, 0, len(podSpec.Containers)+len(podSpec.InitContainers))
+	for _, c := range podSpec.Containers {
+		containers = append(containers, podContainer{Container: c, containerType: ContainerTypeContainer})
+	}
+	for _, c := range podSpec.InitContainers {
+		containerType := ContainerTypeInit
+		if c.RestartPolicy != nil && *c.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			containerType = ContainerTypeSidecar
+		}
+		containers = append(containers, podContainer{Container: c, containerType: containerType})
+	}
+	return containers
+}
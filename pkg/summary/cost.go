@@ -0,0 +1,346 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	kibibyte = 1024
+	mebibyte = kibibyte * 1024
+	gibibyte = mebibyte * 1024
+)
+
+// Limit data loss to only 5% due to rounding error.
+const roundingThreshold = 10
+
+// Price is the hourly cost of a cpu core and of a GB of memory, in the currency of the catalog
+type Price struct {
+	CPU    float64 `json:"cpu"`
+	Memory float64 `json:"memory"`
+}
+
+// LabelPrice is the price of the nodes with a label set to a value
+type LabelPrice struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+	Price
+}
+
+// PricingCatalog is an offline list of prices, so costs can be calculated without
+// access to a pricing service
+type PricingCatalog struct {
+	// Currency is the currency of the prices, USD if not set
+	Currency string `json:"currency,omitempty"`
+	// Default is the price of nodes that don't match any other price
+	Default Price `json:"default"`
+	// InstanceTypeLabel is the node label with the instance type, node.kubernetes.io/instance-type if not set
+	InstanceTypeLabel string `json:"instanceTypeLabel,omitempty"`
+	// InstanceTypes are the prices of nodes by their instance type label
+	InstanceTypes map[string]Price `json:"instanceTypes,omitempty"`
+	// NodeLabels are the prices of nodes with a label, the first match wins. They take
+	// precedence over the instance types.
+	NodeLabels []LabelPrice `json:"nodeLabels,omitempty"`
+}
+
+// LoadPricingCatalog reads a pricing catalog from a YAML or JSON file
+func LoadPricingCatalog(path string) (PricingCatalog, error) {
+	var catalog PricingCatalog
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return catalog, err
+	}
+	if err := yaml.UnmarshalStrict(data, &catalog); err != nil {
+		return catalog, fmt.Errorf("invalid pricing catalog %s: %v", path, err)
+	}
+
+	prices := []Price{catalog.Default}
+	for _, price := range catalog.InstanceTypes {
+		prices = append(prices, price)
+	}
+	for _, labelPrice := range catalog.NodeLabels {
+		if labelPrice.Label == "" {
+			return catalog, fmt.Errorf("invalid pricing catalog %s: node label prices need a label", path)
+		}
+		prices = append(prices, labelPrice.Price)
+	}
+	for _, price := range prices {
+		if price.CPU < 0 || price.Memory < 0 {
+			return catalog, fmt.Errorf("invalid pricing catalog %s: prices can't be negative", path)
+		}
+	}
+	return catalog, nil
+}
+
+// PriceFor returns the price of a node with the labels
+func (c PricingCatalog) PriceFor(nodeLabels map[string]string) Price {
+	for _, labelPrice := range c.NodeLabels {
+		if value, ok := nodeLabels[labelPrice.Label]; ok && value == labelPrice.Value {
+			return labelPrice.Price
+		}
+	}
+	instanceTypeLabel := c.InstanceTypeLabel
+	if instanceTypeLabel == "" {
+		instanceTypeLabel = corev1.LabelInstanceTypeStable
+	}
+	if price, ok := c.InstanceTypes[nodeLabels[instanceTypeLabel]]; ok {
+		return price
+	}
+	return c.Default
+}
+
+// CostPeriod is the period costs are reported for
+type CostPeriod string
+
+const (
+	// CostPerHour reports hourly costs
+	CostPerHour CostPeriod = "hour"
+	// CostPerDay reports daily costs
+	CostPerDay CostPeriod = "day"
+	// CostPerMonth reports monthly costs, of an average month of 730 hours
+	CostPerMonth CostPeriod = "month"
+)
+
+// CostPeriods are all the supported cost periods
+var CostPeriods = []CostPeriod{CostPerHour, CostPerDay, CostPerMonth}
+
+// ParseCostPeriod parses a cost period, an empty period is hourly
+func ParseCostPeriod(value string) (CostPeriod, error) {
+	if value == "" {
+		return CostPerHour, nil
+	}
+	for _, period := range CostPeriods {
+		if CostPeriod(value) == period {
+			return period, nil
+		}
+	}
+	return "", fmt.Errorf("unknown cost period %q, must be one of %v", value, CostPeriods)
+}
+
+// hours is the number of hours in the period
+func (p CostPeriod) hours() float64 {
+	switch p {
+	case CostPerDay:
+		return 24
+	case CostPerMonth:
+		return 730
+	}
+	return 1
+}
+
+// DefaultCurrency is the currency of catalogs without a currency
+const DefaultCurrency = "USD"
+
+// currency returns the currency of the prices of the catalog
+func (c PricingCatalog) currency() string {
+	if c.Currency == "" {
+		return DefaultCurrency
+	}
+	return c.Currency
+}
+
+// FormatCost formats a cost with its currency and period, e.g. 12.5 USD/month
+func FormatCost(cost float64, currency string, period CostPeriod) string {
+	return fmt.Sprintf("%s %s/%s", strconv.FormatFloat(cost, 'f', -1, 64), currency, period)
+}
+
+// Cost is the cost of cpu and memory in the currency and period of the summary
+type Cost struct {
+	CPU    float64 `json:"cpu"`
+	Memory float64 `json:"memory"`
+	Total  float64 `json:"total"`
+}
+
+func newCost(cpu, memory float64) Cost {
+	return Cost{CPU: toFixed(cpu, 4), Memory: toFixed(memory, 4), Total: toFixed(cpu+memory, 4)}
+}
+
+func (c Cost) add(other Cost) Cost {
+	return newCost(c.CPU+other.CPU, c.Memory+other.Memory)
+}
+
+func (c Cost) multiply(factor float64) Cost {
+	return newCost(c.CPU*factor, c.Memory*factor)
+}
+
+// ContainerCosts are the costs of the current resources and of the guaranteed (target) and
+// burstable (between the lower and upper bound) recommendations of all the replicas of a container
+type ContainerCosts struct {
+	Current    Cost `json:"current"`
+	Guaranteed Cost `json:"guaranteed"`
+	Burstable  Cost `json:"burstable"`
+}
+
+// GuaranteedChange is the difference of the guaranteed cost to the current cost
+func (c ContainerCosts) GuaranteedChange() float64 {
+	return toFixed(c.Guaranteed.Total-c.Current.Total, 4)
+}
+
+// BurstableChange is the difference of the burstable cost to the current cost
+func (c ContainerCosts) BurstableChange() float64 {
+	return toFixed(c.Burstable.Total-c.Current.Total, 4)
+}
+
+// CostModel calculates the cost of the current and recommended resources of containers
+type CostModel struct {
+	Catalog PricingCatalog
+	Period  CostPeriod
+}
+
+// NewCostModel returns a CostModel with the prices of the catalog, reporting costs for the period
+func NewCostModel(catalog PricingCatalog, period CostPeriod) *CostModel {
+	if period == "" {
+		period = CostPerHour
+	}
+	return &CostModel{Catalog: catalog, Period: period}
+}
+
+// Currency returns the currency of the costs
+func (m CostModel) Currency() string {
+	return m.Catalog.currency()
+}
+
+// setCosts sets the costs of all the replicas of the container over the period of the model,
+// and the hourly costs of a single replica
+func (m CostModel) setCosts(c *ContainerSummary, price Price, replicas int32) {
+	current := currentCost(price, *c)
+	guaranteed, burstable := recommendedCosts(price, *c)
+
+	c.ContainerCost = current.Total
+	c.GuaranteedCost = math.Abs(toFixed(guaranteed.Total-current.Total, 4))
+	c.BurstableCost = math.Abs(toFixed(burstable.Total-current.Total, 4))
+
+	factor := float64(replicas) * m.Period.hours()
+	c.Costs = &ContainerCosts{
+		Current:    current.multiply(factor),
+		Guaranteed: guaranteed.multiply(factor),
+		Burstable:  burstable.multiply(factor),
+	}
+}
+
+// currentCost is the hourly cost of the average of the requests and limits of the container
+func currentCost(price Price, c ContainerSummary) Cost {
+	var cpuRequests, memRequests, cpuLimits, memLimits float64
+
+	if c.Limits != nil {
+		cpuLimits = float64(c.Limits.Cpu().MilliValue())
+		memLimits = float64(c.Limits.Memory().Value())
+	}
+	if c.Requests != nil {
+		cpuRequests = float64(c.Requests.Cpu().MilliValue())
+		memRequests = float64(c.Requests.Memory().Value())
+	}
+
+	cpuCost := price.CPU * nonZeroAverage(cpuRequests, cpuLimits) / 1000
+	memCost := price.Memory * toGB(int64(nonZeroAverage(memRequests, memLimits)))
+
+	return newCost(cpuCost, memCost)
+}
+
+func nonZeroAverage(req, limit float64) float64 {
+	if req == 0.0 {
+		return limit
+	}
+	if limit == 0.0 {
+		return req
+	}
+	return (req + limit) / 2.0
+}
+
+// recommendedCosts are the hourly costs of the guaranteed and burstable recommendations
+func recommendedCosts(price Price, c ContainerSummary) (Cost, Cost) {
+	guaranteedCPUCost := price.CPU * float64(c.Target.Cpu().MilliValue()) / 1000
+	guaranteedMemCost := price.Memory * toGB(c.Target.Memory().Value())
+
+	burstableCPUCost := price.CPU * (float64(c.LowerBound.Cpu().MilliValue() + c.UpperBound.Cpu().MilliValue())) / 2 / 1000
+	burstableMemCost := price.Memory * (toGB(c.LowerBound.Memory().Value()) + toGB(c.UpperBound.Memory().Value())) / 2
+
+	return newCost(guaranteedCPUCost, guaranteedMemCost), newCost(burstableCPUCost, burstableMemCost)
+}
+
+// workloadPrice returns the price of the nodes the pods are scheduled on, averaged over the pods.
+// Pods that are not scheduled yet are left out, the default price is used when no pod is scheduled.
+func (m CostModel) workloadPrice(pods []unstructured.Unstructured, nodeLabels func(name string) map[string]string) Price {
+	var total Price
+	var scheduled int
+	for _, pod := range pods {
+		nodeName, _, _ := unstructured.NestedString(pod.UnstructuredContent(), "spec", "nodeName")
+		if nodeName == "" {
+			continue
+		}
+		price := m.Catalog.PriceFor(nodeLabels(nodeName))
+		total.CPU += price.CPU
+		total.Memory += price.Memory
+		scheduled++
+	}
+	if scheduled == 0 {
+		return m.Catalog.Default
+	}
+	return Price{
+		CPU:    toFixed(total.CPU/float64(scheduled), 6),
+		Memory: toFixed(total.Memory/float64(scheduled), 6),
+	}
+}
+
+// workloadCost is the cost of the current resources of all the containers of the workload, or nil
+// when they have no costs
+func workloadCost(workload WorkloadSummary) *Cost {
+	var cost *Cost
+	for _, c := range workload.Containers {
+		if c.Costs == nil {
+			continue
+		}
+		if cost == nil {
+			cost = &Cost{}
+		}
+		*cost = cost.add(c.Costs.Current)
+	}
+	return cost
+}
+
+// toGB converts bytes to GB, rounded down to whole GiB, MiB or KiB depending on the size
+func toGB(memoryValue int64) float64 {
+	absoluteValue := memoryValue
+	if absoluteValue < 0 {
+		absoluteValue = -absoluteValue
+	}
+	var roundingBase int64 = 1
+	convertedMemoryValue := float64(memoryValue)
+	if absoluteValue > gibibyte*roundingThreshold {
+		convertedMemoryValue = float64((memoryValue / gibibyte) * roundingBase)
+	} else if absoluteValue > mebibyte*roundingThreshold {
+		convertedMemoryValue = float64(((memoryValue / mebibyte) * roundingBase)) / 1024
+	} else if absoluteValue > kibibyte*roundingThreshold {
+		convertedMemoryValue = float64(((memoryValue / kibibyte) * roundingBase)) / (1024 * 1024)
+	}
+	return convertedMemoryValue
+}
+
+func toFixed(num float64, precision int) float64 {
+	output := math.Pow(10, float64(precision))
+	return float64(round(num*output)) / output
+}
+
+func round(num float64) int {
+	return int(num + math.Copysign(0.5, num))
+}
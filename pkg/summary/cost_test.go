@@ -0,0 +1,178 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/fairwindsops/goldilocks/pkg/kube"
+)
+
+func writeCatalog(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "catalog.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadPricingCatalog(t *testing.T) {
+	path := writeCatalog(t, `
+default:
+  cpu: 0.03
+  memory: 0.004
+instanceTypes:
+  m5.large:
+    cpu: 0.04
+    memory: 0.005
+nodeLabels:
+- label: karpenter.sh/capacity-type
+  value: spot
+  cpu: 0.01
+  memory: 0.001
+`)
+	catalog, err := LoadPricingCatalog(path)
+	assert.NoError(t, err)
+	assert.Equal(t, Price{CPU: 0.03, Memory: 0.004}, catalog.Default)
+	assert.Equal(t, Price{CPU: 0.04, Memory: 0.005}, catalog.InstanceTypes["m5.large"])
+	assert.Equal(t, LabelPrice{Label: "karpenter.sh/capacity-type", Value: "spot", Price: Price{CPU: 0.01, Memory: 0.001}}, catalog.NodeLabels[0])
+
+	invalid := map[string]string{
+		"unknown field":  "default:\n  cpu: 1\n  gpu: 2\n",
+		"negative price": "instanceTypes:\n  m5.large:\n    cpu: -1\n",
+		"missing label":  "nodeLabels:\n- value: spot\n  cpu: 1\n",
+	}
+	for name, content := range invalid {
+		_, err := LoadPricingCatalog(writeCatalog(t, content))
+		assert.Error(t, err, name)
+	}
+
+	_, err = LoadPricingCatalog(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestPriceFor(t *testing.T) {
+	catalog := PricingCatalog{
+		Default:       Price{CPU: 1, Memory: 1},
+		InstanceTypes: map[string]Price{"m5.large": {CPU: 2, Memory: 2}},
+		NodeLabels:    []LabelPrice{{Label: "capacity-type", Value: "spot", Price: Price{CPU: 3, Memory: 3}}},
+	}
+
+	assert.Equal(t, Price{CPU: 1, Memory: 1}, catalog.PriceFor(nil))
+	assert.Equal(t, Price{CPU: 1, Memory: 1}, catalog.PriceFor(map[string]string{corev1.LabelInstanceTypeStable: "unknown"}))
+	assert.Equal(t, Price{CPU: 2, Memory: 2}, catalog.PriceFor(map[string]string{corev1.LabelInstanceTypeStable: "m5.large"}))
+	assert.Equal(t, Price{CPU: 3, Memory: 3}, catalog.PriceFor(map[string]string{corev1.LabelInstanceTypeStable: "m5.large", "capacity-type": "spot"}))
+	assert.Equal(t, Price{CPU: 2, Memory: 2}, catalog.PriceFor(map[string]string{corev1.LabelInstanceTypeStable: "m5.large", "capacity-type": "on-demand"}))
+
+	catalog.InstanceTypeLabel = "example.com/instance-type"
+	assert.Equal(t, Price{CPU: 1, Memory: 1}, catalog.PriceFor(map[string]string{corev1.LabelInstanceTypeStable: "m5.large"}))
+	assert.Equal(t, Price{CPU: 2, Memory: 2}, catalog.PriceFor(map[string]string{"example.com/instance-type": "m5.large"}))
+}
+
+func TestWorkloadPrice(t *testing.T) {
+	kubeClient := kube.GetMockClient()
+	for name, capacityType := range map[string]string{"node-spot": "spot", "node-on-demand": "on-demand"} {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"capacity-type": capacityType}}}
+		_, err := kubeClient.Client.CoreV1().Nodes().Create(t.Context(), node, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+	summarizer := Summarizer{options: options{kubeClient: kubeClient}}
+	nodes := map[string]*corev1.Node{}
+	nodeLabels := func(name string) map[string]string {
+		return summarizer.getNode(name, nodes).GetLabels()
+	}
+
+	pod := func(nodeName string) unstructured.Unstructured {
+		return unstructured.Unstructured{Object: map[string]any{"spec": map[string]any{"nodeName": nodeName}}}
+	}
+	model := NewCostModel(PricingCatalog{
+		Default:    Price{CPU: 0.04, Memory: 0.004},
+		NodeLabels: []LabelPrice{{Label: "capacity-type", Value: "spot", Price: Price{CPU: 0.01, Memory: 0.001}}},
+	}, CostPerHour)
+
+	// two pods on spot, one on demand and one pending
+	pods := []unstructured.Unstructured{pod("node-spot"), pod("node-spot"), pod("node-on-demand"), pod("")}
+	assert.Equal(t, Price{CPU: 0.02, Memory: 0.002}, model.workloadPrice(pods, nodeLabels))
+	assert.Equal(t, Price{CPU: 0.04, Memory: 0.004}, model.workloadPrice([]unstructured.Unstructured{pod("")}, nodeLabels))
+	// nodes that are gone have the default price
+	assert.Equal(t, Price{CPU: 0.04, Memory: 0.004}, model.workloadPrice([]unstructured.Unstructured{pod("node-deleted")}, nodeLabels))
+	assert.Len(t, nodes, 3)
+}
+
+func TestWorkloadCost(t *testing.T) {
+	workload := WorkloadSummary{
+		Containers: map[string]ContainerSummary{
+			"app":     {Costs: &ContainerCosts{Current: newCost(0.3, 0.1)}},
+			"sidecar": {Costs: &ContainerCosts{Current: newCost(0.02, 0.0123)}},
+		},
+	}
+	assert.Equal(t, &Cost{CPU: 0.32, Memory: 0.1123, Total: 0.4323}, workloadCost(workload))
+
+	assert.Nil(t, workloadCost(WorkloadSummary{Containers: map[string]ContainerSummary{"app": {}}}))
+}
+
+func TestSetCosts(t *testing.T) {
+	c := ContainerSummary{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+			corev1.ResourceMemory: resource.MustParse("100Mi"),
+		},
+		Target: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("200m"),
+			corev1.ResourceMemory: resource.MustParse("100Mi"),
+		},
+		LowerBound: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("50m"),
+			corev1.ResourceMemory: resource.MustParse("50Mi"),
+		},
+		UpperBound: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("50m"),
+			corev1.ResourceMemory: resource.MustParse("50Mi"),
+		},
+	}
+
+	NewCostModel(PricingCatalog{}, CostPerDay).setCosts(&c, Price{CPU: 1, Memory: 1}, 2)
+
+	// hourly costs of a single replica
+	assert.Equal(t, 0.1977, c.ContainerCost)
+	assert.Equal(t, 0.1, c.GuaranteedCost)
+	assert.Equal(t, 0.0989, c.BurstableCost)
+
+	// daily costs of both replicas
+	assert.Equal(t, Cost{CPU: 4.8, Memory: 4.6896, Total: 9.4896}, c.Costs.Current)
+	assert.Equal(t, Cost{CPU: 9.6, Memory: 4.6896, Total: 14.2896}, c.Costs.Guaranteed)
+	assert.Equal(t, Cost{CPU: 2.4, Memory: 2.3424, Total: 4.7424}, c.Costs.Burstable)
+	assert.Equal(t, 4.8, c.Costs.GuaranteedChange())
+	assert.Equal(t, -4.7472, c.Costs.BurstableChange())
+}
+
+func TestParseCostPeriod(t *testing.T) {
+	for value, want := range map[string]CostPeriod{"": CostPerHour, "hour": CostPerHour, "day": CostPerDay, "month": CostPerMonth} {
+		got, err := ParseCostPeriod(value)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := ParseCostPeriod("week")
+	assert.Error(t, err)
+
+	assert.Equal(t, 730.0, CostPerMonth.hours())
+	assert.Equal(t, "12.5 EUR/month", FormatCost(12.5, "EUR", CostPerMonth))
+}
@@ -0,0 +1,284 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/fairwindsops/goldilocks/pkg/utils"
+)
+
+// DifferenceType is the kind of change between two summaries
+type DifferenceType string
+
+const (
+	// DifferenceAdded is a workload or container that is only in the new summary
+	DifferenceAdded DifferenceType = "added"
+	// DifferenceRemoved is a workload or container that is only in the old summary
+	DifferenceRemoved DifferenceType = "removed"
+	// DifferenceRecommendation is a change of the target beyond the threshold
+	DifferenceRecommendation DifferenceType = "recommendation"
+	// DifferenceRequests is a change of the current requests
+	DifferenceRequests DifferenceType = "requests"
+	// DifferenceLimits is a change of the current limits
+	DifferenceLimits DifferenceType = "limits"
+)
+
+// DiffFormat is the output format of the differences
+type DiffFormat string
+
+const (
+	// DiffText writes a line per difference
+	DiffText DiffFormat = "text"
+	// DiffJSON writes the differences as a JSON array
+	DiffJSON DiffFormat = "json"
+)
+
+// DiffFormats are all the supported output formats of the differences
+var DiffFormats = []DiffFormat{DiffText, DiffJSON}
+
+// Difference is a single change between two summaries. Container is empty for workloads that were
+// added or removed, and Resource is only set for changes of a resource value.
+type Difference struct {
+	Type      DifferenceType      `json:"type"`
+	Namespace string              `json:"namespace"`
+	Kind      string              `json:"kind"`
+	Workload  string              `json:"workload"`
+	Container string              `json:"container,omitempty"`
+	Resource  corev1.ResourceName `json:"resource,omitempty"`
+	From      string              `json:"from,omitempty"`
+	To        string              `json:"to,omitempty"`
+	// Percent is the change of a recommendation as a percentage of the old value
+	Percent float64 `json:"percent,omitempty"`
+	// WithinTolerance is set for changes of the current requests and limits within the tolerance of the old value
+	WithinTolerance bool `json:"withinTolerance,omitempty"`
+}
+
+// String formats the difference as a single line
+func (d Difference) String() string {
+	name := strings.Join([]string{d.Namespace, d.Kind, d.Workload}, "/")
+	if d.Container != "" {
+		name += "/" + d.Container
+	}
+	switch d.Type {
+	case DifferenceAdded:
+		return fmt.Sprintf("+ %s added", name)
+	case DifferenceRemoved:
+		return fmt.Sprintf("- %s removed", name)
+	case DifferenceRecommendation:
+		return fmt.Sprintf("~ %s recommended %s: %s -> %s (%+.0f%%)", name, d.Resource, d.From, d.To, d.Percent)
+	}
+	line := fmt.Sprintf("~ %s %s %s: %s -> %s", name, d.Type, d.Resource, d.From, d.To)
+	if d.WithinTolerance {
+		line += " (within tolerance)"
+	}
+	return line
+}
+
+// OutsideTolerance returns true if any difference is not a change within the tolerance
+func OutsideTolerance(differences []Difference) bool {
+	for _, d := range differences {
+		if !d.WithinTolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff compares two summaries. Changes of the target are reported when they are more than
+// threshold percent of the old target, changes of the current requests and limits are always reported
+// and marked when they are within the tolerance of the old value.
+func Diff(old, new Summary, threshold float64, tolerance utils.Tolerance) []Difference {
+	var differences []Difference
+
+	for _, key := range workloadKeys(old, new) {
+		oldWorkload, inOld := findWorkload(old, key)
+		newWorkload, inNew := findWorkload(new, key)
+		base := Difference{Namespace: key.namespace, Kind: key.kind, Workload: key.name}
+		switch {
+		case !inNew:
+			differences = append(differences, base.with(DifferenceRemoved))
+			continue
+		case !inOld:
+			differences = append(differences, base.with(DifferenceAdded))
+			continue
+		}
+
+		for _, containerName := range containerNames(oldWorkload, newWorkload) {
+			oldContainer, inOld := oldWorkload.Containers[containerName]
+			newContainer, inNew := newWorkload.Containers[containerName]
+			container := base
+			container.Container = containerName
+			switch {
+			case !inNew:
+				differences = append(differences, container.with(DifferenceRemoved))
+				continue
+			case !inOld:
+				differences = append(differences, container.with(DifferenceAdded))
+				continue
+			}
+
+			differences = append(differences, diffResourceLists(container.with(DifferenceRecommendation), oldContainer.Target, newContainer.Target, threshold, nil)...)
+			differences = append(differences, diffResourceLists(container.with(DifferenceRequests), oldContainer.Requests, newContainer.Requests, -1, &tolerance)...)
+			differences = append(differences, diffResourceLists(container.with(DifferenceLimits), oldContainer.Limits, newContainer.Limits, -1, &tolerance)...)
+		}
+	}
+	return differences
+}
+
+func (d Difference) with(t DifferenceType) Difference {
+	d.Type = t
+	return d
+}
+
+// diffResourceLists reports the resources whose value changed by more than threshold percent.
+// A negative threshold reports every change. With a tolerance, changes within it are marked.
+func diffResourceLists(base Difference, old, new corev1.ResourceList, threshold float64, tolerance *utils.Tolerance) []Difference {
+	names := map[corev1.ResourceName]bool{}
+	for name := range old {
+		names[name] = true
+	}
+	for name := range new {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, string(name))
+	}
+	sort.Strings(sorted)
+
+	var differences []Difference
+	for _, name := range sorted {
+		oldValue, newValue := old[corev1.ResourceName(name)], new[corev1.ResourceName(name)]
+		if oldValue.Cmp(newValue) == 0 {
+			continue
+		}
+		percent := percentChange(oldValue, newValue)
+		if threshold >= 0 && math.Abs(percent) <= threshold {
+			continue
+		}
+		d := base
+		d.Resource = corev1.ResourceName(name)
+		d.From = formatQuantity(oldValue)
+		d.To = formatQuantity(newValue)
+		if base.Type == DifferenceRecommendation {
+			d.Percent = math.Round(percent*10) / 10
+		}
+		if tolerance != nil {
+			d.WithinTolerance = tolerance.Within(d.Resource, newValue, oldValue)
+		}
+		differences = append(differences, d)
+	}
+	return differences
+}
+
+// percentChange is the change from old to new as a percentage of old, or 100% for new values
+func percentChange(old, new resource.Quantity) float64 {
+	if old.IsZero() {
+		return 100
+	}
+	d := new.DeepCopy()
+	d.Sub(old)
+	return 100 * float64(d.MilliValue()) / float64(old.MilliValue())
+}
+
+type workloadKey struct {
+	namespace string
+	kind      string
+	name      string
+}
+
+// workloadKeys returns the workloads of both summaries, sorted by namespace, kind and name
+func workloadKeys(summaries ...Summary) []workloadKey {
+	seen := map[workloadKey]bool{}
+	var keys []workloadKey
+	for _, s := range summaries {
+		for _, ns := range s.Namespaces {
+			for _, workload := range ns.Workloads {
+				key := workloadKey{namespace: ns.Namespace, kind: workload.ControllerType, name: workload.ControllerName}
+				if !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].namespace != keys[j].namespace {
+			return keys[i].namespace < keys[j].namespace
+		}
+		if keys[i].kind != keys[j].kind {
+			return keys[i].kind < keys[j].kind
+		}
+		return keys[i].name < keys[j].name
+	})
+	return keys
+}
+
+func findWorkload(s Summary, key workloadKey) (WorkloadSummary, bool) {
+	for _, workload := range s.Namespaces[key.namespace].Workloads {
+		if workload.ControllerType == key.kind && workload.ControllerName == key.name {
+			return workload, true
+		}
+	}
+	return WorkloadSummary{}, false
+}
+
+func containerNames(workloads ...WorkloadSummary) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, workload := range workloads {
+		for name := range workload.Containers {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WriteDiff writes the differences to the writer in the given format
+func WriteDiff(w io.Writer, differences []Difference, format DiffFormat) error {
+	switch format {
+	case DiffJSON:
+		if differences == nil {
+			differences = []Difference{}
+		}
+		encoded, err := json.MarshalIndent(differences, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(encoded))
+		return err
+	case DiffText:
+		for _, d := range differences {
+			if _, err := fmt.Fprintln(w, d.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, must be one of %v", format, DiffFormats)
+}
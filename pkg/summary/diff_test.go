@@ -0,0 +1,124 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fairwindsops/goldilocks/pkg/utils"
+)
+
+const testNewSummaryJSON = `{
+  "apiVersion": "goldilocks.fairwinds.com/v1",
+  "namespaces": {
+    "testing": {
+      "namespace": "testing",
+      "workloads": {
+        "web": {
+          "controllerName": "web",
+          "controllerType": "Deployment",
+          "containers": {
+            "app": {
+              "containerName": "app",
+              "target": {"cpu": "105m", "memory": "256Mi"},
+              "requests": {"cpu": "100m", "memory": "128Mi"},
+              "limits": {"cpu": "1", "memory": "256Mi"}
+            },
+            "proxy": {
+              "containerName": "proxy",
+              "target": {"cpu": "10m", "memory": "16Mi"}
+            }
+          }
+        },
+        "worker": {
+          "controllerName": "worker",
+          "controllerType": "Deployment",
+          "containers": {}
+        }
+      }
+    }
+  }
+}`
+
+func TestDiff(t *testing.T) {
+	old := testOutputSummary(t)
+	var new Summary
+	require.NoError(t, json.Unmarshal([]byte(testNewSummaryJSON), &new))
+
+	differences := Diff(old, new, 10, utils.Tolerance{})
+	var lines []string
+	for _, d := range differences {
+		lines = append(lines, d.String())
+	}
+	assert.Equal(t, []string{
+		"~ testing/Deployment/web/app recommended memory: 128Mi -> 256Mi (+100%)",
+		"~ testing/Deployment/web/app requests cpu: 500m -> 100m",
+		"+ testing/Deployment/web/proxy added",
+		"+ testing/Deployment/worker added",
+		"- testing/StatefulSet/db removed",
+	}, lines)
+
+	// the cpu recommendation changed by 5%
+	assert.Len(t, Diff(old, new, 1, utils.Tolerance{}), 6)
+	assert.Empty(t, Diff(old, old, 0, utils.Tolerance{}))
+
+	var text bytes.Buffer
+	require.NoError(t, WriteDiff(&text, differences[:1], DiffText))
+	assert.Equal(t, "~ testing/Deployment/web/app recommended memory: 128Mi -> 256Mi (+100%)\n", text.String())
+
+	var encoded bytes.Buffer
+	require.NoError(t, WriteDiff(&encoded, differences[:1], DiffJSON))
+	var decoded []Difference
+	require.NoError(t, json.Unmarshal(encoded.Bytes(), &decoded))
+	assert.Equal(t, differences[:1], decoded)
+
+	var empty bytes.Buffer
+	require.NoError(t, WriteDiff(&empty, nil, DiffJSON))
+	assert.Equal(t, "[]\n", empty.String())
+
+	assert.Error(t, WriteDiff(&empty, nil, DiffFormat("bogus")))
+}
+
+func TestDiffTolerance(t *testing.T) {
+	old := testOutputSummary(t)
+	var new Summary
+	require.NoError(t, json.Unmarshal([]byte(testNewSummaryJSON), &new))
+	tolerance, err := utils.ParseTolerance("cpu=80%")
+	require.NoError(t, err)
+
+	// the cpu request changed by 400m, within 80% of 500m
+	var requests []Difference
+	for _, d := range Diff(old, new, 10, tolerance) {
+		if d.Type == DifferenceRequests {
+			requests = append(requests, d)
+		}
+	}
+	require.Len(t, requests, 1)
+	assert.True(t, requests[0].WithinTolerance)
+	assert.Equal(t, "~ testing/Deployment/web/app requests cpu: 500m -> 100m (within tolerance)", requests[0].String())
+	assert.False(t, OutsideTolerance(requests))
+
+	// without a tolerance every change is outside it
+	for _, d := range Diff(old, new, 10, utils.Tolerance{}) {
+		assert.False(t, d.WithinTolerance, d.String())
+	}
+	assert.True(t, OutsideTolerance(Diff(old, new, 10, tolerance)))
+	assert.False(t, OutsideTolerance(nil))
+}
@@ -0,0 +1,207 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"math"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+)
+
+// hasFilters returns true if the summary is limited to some of the workloads
+func (s Summarizer) hasFilters() bool {
+	return len(s.kinds) > 0 || !isEmptySelector(s.workloadSelector) || !isEmptySelector(s.namespaceSelector) || s.minDifference > 0
+}
+
+// matchesFilters returns true if the workload of the vpa matches the kind, namespace and workload filters
+func (s Summarizer) matchesFilters(vpa vpav1.VerticalPodAutoscaler, namespaces map[string]*corev1.Namespace) bool {
+	if len(s.kinds) > 0 && (vpa.Spec.TargetRef == nil || !s.kinds.Has(vpa.Spec.TargetRef.Kind)) {
+		return false
+	}
+	if !isEmptySelector(s.namespaceSelector) {
+		ns := s.getNamespace(vpa.Namespace, namespaces)
+		if !s.namespaceSelector.Matches(labels.Set(ns.GetLabels())) {
+			return false
+		}
+	}
+	if !isEmptySelector(s.workloadSelector) {
+		workload, ok := s.workloadForVPANamed[vpaKey(vpa)]
+		if !ok || !s.workloadSelector.Matches(labels.Set(workload.TopController.GetLabels())) {
+			return false
+		}
+	}
+	return true
+}
+
+func isEmptySelector(selector labels.Selector) bool {
+	return selector == nil || selector.Empty()
+}
+
+// difference is the largest difference between the current and recommended cpu or memory request,
+// as a percentage of the recommendation
+func (c ContainerSummary) difference() float64 {
+	recommended := c.recommendedRequests()
+	return 100 * math.Max(
+		relativeDelta(c.Requests[corev1.ResourceCPU], recommended[corev1.ResourceCPU]),
+		relativeDelta(c.Requests[corev1.ResourceMemory], recommended[corev1.ResourceMemory]),
+	)
+}
+
+// recommendedRequests are the recommended requests of the limit strategy, or the target without one
+func (c ContainerSummary) recommendedRequests() corev1.ResourceList {
+	if len(c.RecommendedRequests) > 0 {
+		return c.RecommendedRequests
+	}
+	return c.Target
+}
+
+// Top returns a copy of the summary with only the n containers that come first in the sort order.
+// For the over and under provisioning orders only the containers that are over or under provisioned are kept.
+func Top(data Summary, sortBy SortBy, n int) Summary {
+	rows := Rows(data)
+	SortRows(rows, sortBy)
+
+	top := emptyCopy(data)
+	for _, row := range rows {
+		if n <= 0 {
+			break
+		}
+		if !isProvisioned(row, sortBy) {
+			continue
+		}
+		n--
+
+		source := data.Namespaces[row.Namespace]
+		ns, ok := top.Namespaces[row.Namespace]
+		if !ok {
+			ns = source
+			ns.Workloads = map[string]WorkloadSummary{}
+		}
+		workload, ok := ns.Workloads[row.key]
+		if !ok {
+			workload = source.Workloads[row.key]
+			workload.Containers = map[string]ContainerSummary{}
+		}
+		workload.Containers[row.Container] = source.Workloads[row.key].Containers[row.Container]
+		ns.Workloads[row.key] = workload
+		top.Namespaces[row.Namespace] = ns
+	}
+	setTotals(&top)
+	setIsOnlyNamespace(&top)
+	return top
+}
+
+// Page returns a copy of the summary with only the limit workloads after the offset, in the order of
+// their namespaces and keys, and the number of workloads in the summary. A limit of 0 keeps all the
+// workloads after the offset.
+func Page(data Summary, offset, limit int) (Summary, int) {
+	type workloadRef struct {
+		namespace string
+		key       string
+	}
+	var refs []workloadRef
+	for nsName, ns := range data.Namespaces {
+		for key := range ns.Workloads {
+			refs = append(refs, workloadRef{namespace: nsName, key: key})
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].namespace != refs[j].namespace {
+			return refs[i].namespace < refs[j].namespace
+		}
+		return refs[i].key < refs[j].key
+	})
+
+	total := len(refs)
+	refs = refs[min(offset, total):]
+	if limit > 0 && limit < len(refs) {
+		refs = refs[:limit]
+	}
+
+	page := emptyCopy(data)
+	for _, ref := range refs {
+		ns, ok := page.Namespaces[ref.namespace]
+		if !ok {
+			ns = data.Namespaces[ref.namespace]
+			ns.Workloads = map[string]WorkloadSummary{}
+		}
+		ns.Workloads[ref.key] = data.Namespaces[ref.namespace].Workloads[ref.key]
+		page.Namespaces[ref.namespace] = ns
+	}
+	setTotals(&page)
+	setIsOnlyNamespace(&page)
+	return page, total
+}
+
+// Filter returns a copy of the summary with only the namespaces and workloads that are kept, and the
+// warnings about them. Kept namespaces stay in the summary when none of their workloads are kept.
+func Filter(data Summary, keepNamespace func(namespace string) bool, keepWorkload func(namespace string, workload WorkloadSummary) bool) Summary {
+	filtered := emptyCopy(data)
+	filtered.Warnings = nil
+	for nsName, source := range data.Namespaces {
+		if !keepNamespace(nsName) {
+			continue
+		}
+		ns := source
+		ns.Workloads = map[string]WorkloadSummary{}
+		for key, workload := range source.Workloads {
+			if keepWorkload(nsName, workload) {
+				ns.Workloads[key] = workload
+			}
+		}
+		filtered.Namespaces[nsName] = ns
+	}
+	for _, warning := range data.Warnings {
+		if _, ok := findWorkload(filtered, workloadKey{namespace: warning.Namespace, kind: warning.Kind, name: warning.Workload}); ok {
+			filtered.Warnings = append(filtered.Warnings, warning)
+		}
+	}
+	setTotals(&filtered)
+	setIsOnlyNamespace(&filtered)
+	return filtered
+}
+
+// emptyCopy returns a copy of the summary without namespaces
+func emptyCopy(data Summary) Summary {
+	return Summary{
+		APIVersion: data.APIVersion,
+		Namespaces: map[string]NamespaceSummary{},
+		Warnings:   data.Warnings,
+		Currency:   data.Currency,
+		CostPeriod: data.CostPeriod,
+	}
+}
+
+// isProvisioned returns true if the row is over or under provisioned in the direction of the sort order
+func isProvisioned(row Row, sortBy SortBy) bool {
+	switch sortBy {
+	case SortByCPUOver:
+		cpuDelta := row.CPUDelta()
+		return cpuDelta.Sign() < 0
+	case SortByCPUUnder:
+		cpuDelta := row.CPUDelta()
+		return cpuDelta.Sign() > 0
+	case SortByMemoryOver:
+		memDelta := row.MemoryDelta()
+		return memDelta.Sign() < 0
+	case SortByMemoryUnder:
+		memDelta := row.MemoryDelta()
+		return memDelta.Sign() > 0
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/fairwindsops/goldilocks/pkg/kube"
+)
+
+func Test_Summarizer_Filters(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          []Option
+		wantWorkloads int
+	}{
+		{name: "no filters", wantWorkloads: 1},
+		{name: "matching kind", opts: []Option{ForKinds(sets.New("DaemonSet"))}, wantWorkloads: 1},
+		{name: "other kind", opts: []Option{ForKinds(sets.New("Deployment"))}},
+		{name: "matching namespace selector", opts: []Option{ForNamespacesMatching(labels.SelectorFromSet(labels.Set{"team": "a"}))}, wantWorkloads: 1},
+		{name: "other namespace selector", opts: []Option{ForNamespacesMatching(labels.SelectorFromSet(labels.Set{"team": "b"}))}},
+		{name: "matching workload selector", opts: []Option{ForWorkloadsMatching(labels.SelectorFromSet(labels.Set{"app": "agent"}))}, wantWorkloads: 1},
+		{name: "other workload selector", opts: []Option{ForWorkloadsMatching(labels.SelectorFromSet(labels.Set{"app": "web"}))}},
+		{name: "requests equal to the target", opts: []Option{WithMinimumDifference(10)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClientVPA := kube.GetMockVPAClient()
+			kubeClient := kube.GetMockClient()
+			dynamicClient := kube.GetMockDynamicClient()
+			controllerUtilsClient := kube.GetMockControllerUtilsClient(dynamicClient)
+
+			summarizer := NewSummarizer(tt.opts...)
+			summarizer.kubeClient = kubeClient
+			summarizer.vpaClient = kubeClientVPA
+			summarizer.dynamicClient = dynamicClient
+			summarizer.controllerUtilsClient = controllerUtilsClient
+
+			ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "testing-daemonset", Labels: map[string]string{"team": "a"}}}
+			_, err := kubeClient.Client.CoreV1().Namespaces().Create(context.TODO(), ns, metav1.CreateOptions{})
+			assert.NoError(t, err)
+			daemonSet := testDaemonSettWithRecoUnstructured.DeepCopy()
+			daemonSet.SetLabels(map[string]string{"app": "agent"})
+			_, err = dynamicClient.Client.Resource(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}).Namespace("testing-daemonset").Create(context.TODO(), daemonSet, metav1.CreateOptions{})
+			assert.NoError(t, err)
+			_, err = dynamicClient.Client.Resource(schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}).Namespace("testing-daemonset").Create(context.TODO(), testDaemonSetWithRecoPodUnstructured, metav1.CreateOptions{})
+			assert.NoError(t, err)
+			_, err = kubeClientVPA.Client.AutoscalingV1().VerticalPodAutoscalers("testing-daemonset").Create(context.TODO(), testDaemonSetVPAWithReco, metav1.CreateOptions{})
+			assert.NoError(t, err)
+
+			got, err := summarizer.GetSummary()
+			assert.NoError(t, err)
+			if tt.wantWorkloads == 0 {
+				assert.Empty(t, got.Namespaces)
+				return
+			}
+			assert.Len(t, got.Namespaces["testing-daemonset"].Workloads, tt.wantWorkloads)
+		})
+	}
+}
+
+func TestTop(t *testing.T) {
+	data := testOutputSummary(t)
+
+	over := Top(data, SortByCPUOver, 5)
+	require.Len(t, over.Namespaces, 1)
+	assert.Len(t, over.Namespaces["testing"].Workloads, 1)
+	assert.Contains(t, over.Namespaces["testing"].Workloads["web"].Containers, "app")
+
+	under := Top(data, SortByMemoryUnder, 5)
+	assert.Len(t, under.Namespaces["testing"].Workloads, 1)
+	assert.Contains(t, under.Namespaces["testing"].Workloads["db"].Containers, "postgres")
+
+	first := Top(data, SortByDelta, 1)
+	assert.Len(t, first.Namespaces["testing"].Workloads, 1)
+	assert.Contains(t, first.Namespaces["testing"].Workloads, "web")
+
+	assert.Len(t, data.Namespaces["testing"].Workloads, 2, "the summary is not modified")
+}
+
+func TestPage(t *testing.T) {
+	data := testOutputSummary(t)
+	data.Currency = "EUR"
+	data.Namespaces["other"] = NamespaceSummary{Namespace: "other", Workloads: map[string]WorkloadSummary{}}
+
+	first, total := Page(data, 0, 1)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, []string{"db"}, workloadNames(first))
+	assert.Equal(t, int32(2), first.Totals.Replicas)
+	assert.Equal(t, "EUR", first.Currency)
+	require.Len(t, first.Namespaces, 1)
+	assert.True(t, first.Namespaces["testing"].IsOnlyNamespace, "the only namespace of the page is marked")
+
+	second, _ := Page(data, 1, 1)
+	assert.Equal(t, []string{"web"}, workloadNames(second))
+
+	all, _ := Page(data, 0, 0)
+	assert.Equal(t, []string{"db", "web"}, workloadNames(all))
+
+	past, total := Page(data, 5, 1)
+	assert.Equal(t, 2, total)
+	assert.Empty(t, past.Namespaces)
+}
+
+func TestFilter(t *testing.T) {
+	data := testOutputSummary(t)
+	data.Namespaces["other"] = NamespaceSummary{Namespace: "other", Workloads: map[string]WorkloadSummary{}}
+	data.Warnings = []Warning{
+		{Type: WorkloadStatusNoRecommendationYet, Namespace: "testing", Kind: "Deployment", Workload: "web"},
+		{Type: WorkloadStatusNoRecommendationYet, Namespace: "testing", Kind: "StatefulSet", Workload: "db"},
+	}
+
+	filtered := Filter(data,
+		func(namespace string) bool { return namespace == "testing" },
+		func(namespace string, workload WorkloadSummary) bool { return workload.ControllerName == "web" })
+	assert.Equal(t, []string{"web"}, workloadNames(filtered))
+	assert.NotContains(t, filtered.Namespaces, "other")
+	assert.Equal(t, int32(3), filtered.Totals.Replicas)
+	require.Len(t, filtered.Warnings, 1)
+	assert.Equal(t, "web", filtered.Warnings[0].Workload)
+	assert.True(t, filtered.Namespaces["testing"].IsOnlyNamespace, "the only namespace left is marked")
+
+	none := Filter(data,
+		func(namespace string) bool { return true },
+		func(namespace string, workload WorkloadSummary) bool { return false })
+	assert.Len(t, none.Namespaces, 2, "namespaces are kept without workloads")
+	assert.False(t, none.Namespaces["testing"].IsOnlyNamespace)
+	assert.False(t, none.Namespaces["other"].IsOnlyNamespace)
+	assert.Empty(t, none.Warnings)
+	assert.Len(t, data.Namespaces["testing"].Workloads, 2, "the summary is not modified")
+}
+
+func workloadNames(data Summary) []string {
+	var names []string
+	for _, ns := range data.Namespaces {
+		for key := range ns.Workloads {
+			names = append(names, key)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestDifference(t *testing.T) {
+	data := testOutputSummary(t)
+	assert.InDelta(t, 400.0, data.Namespaces["testing"].Workloads["web"].Containers["app"].difference(), 0.001)
+	assert.InDelta(t, 50.0, data.Namespaces["testing"].Workloads["db"].Containers["postgres"].difference(), 0.001)
+}
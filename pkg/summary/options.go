@@ -3,6 +3,7 @@ package summary
 import (
 	"github.com/fairwindsops/goldilocks/pkg/kube"
 	"github.com/fairwindsops/goldilocks/pkg/utils"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/sets"
 )
 
@@ -17,6 +18,16 @@ type options struct {
 	namespace             string
 	vpaLabels             map[string]string
 	excludedContainers    sets.Set[string]
+	tolerance             utils.Tolerance
+	rounding              utils.RoundingPolicy
+	headroom              utils.Headroom
+	limitStrategy         utils.LimitStrategy
+	workloadSelector      labels.Selector
+	namespaceSelector     labels.Selector
+	kinds                 sets.Set[string]
+	minDifference         float64
+	costModel             *CostModel
+	cache                 *Cache
 }
 
 // defaultOptions for a Summarizer
@@ -52,3 +63,77 @@ func ForVPAsWithLabels(vpaLabels map[string]string) Option {
 		opts.vpaLabels = vpaLabels
 	}
 }
+
+// WithTolerance is an Option for treating current values within the tolerance of the target as equal
+func WithTolerance(tolerance utils.Tolerance) Option {
+	return func(opts *options) {
+		opts.tolerance = tolerance
+	}
+}
+
+// WithRoundingPolicy is an Option for rounding the recommendations in the summary
+func WithRoundingPolicy(rounding utils.RoundingPolicy) Option {
+	return func(opts *options) {
+		opts.rounding = rounding
+	}
+}
+
+// WithHeadroom is an Option for adding a safety margin on top of the target of workloads
+// and namespaces without a headroom annotation
+func WithHeadroom(headroom utils.Headroom) Option {
+	return func(opts *options) {
+		opts.headroom = headroom
+	}
+}
+
+// WithLimitStrategy is an Option for deriving recommended requests and limits
+// in namespaces without a limit strategy annotation
+func WithLimitStrategy(strategy utils.LimitStrategy) Option {
+	return func(opts *options) {
+		opts.limitStrategy = strategy
+	}
+}
+
+// ForWorkloadsMatching is an Option for limiting the summary to workloads with labels matching the selector
+func ForWorkloadsMatching(selector labels.Selector) Option {
+	return func(opts *options) {
+		opts.workloadSelector = selector
+	}
+}
+
+// ForNamespacesMatching is an Option for limiting the summary to namespaces with labels matching the selector
+func ForNamespacesMatching(selector labels.Selector) Option {
+	return func(opts *options) {
+		opts.namespaceSelector = selector
+	}
+}
+
+// ForKinds is an Option for limiting the summary to workloads of certain kinds, e.g. Deployment
+func ForKinds(kinds sets.Set[string]) Option {
+	return func(opts *options) {
+		opts.kinds = kinds
+	}
+}
+
+// WithMinimumDifference is an Option for limiting the summary to containers whose cpu or memory
+// request differs from the recommendation by more than the percentage
+func WithMinimumDifference(percent float64) Option {
+	return func(opts *options) {
+		opts.minDifference = percent
+	}
+}
+
+// WithCostModel is an Option for calculating the cost of the containers with the cost model
+func WithCostModel(model *CostModel) Option {
+	return func(opts *options) {
+		opts.costModel = model
+	}
+}
+
+// FromCache is an Option for summarizing the VPAs and workloads of the cache while it is synced and
+// not stale, instead of listing them from the cluster
+func FromCache(cache *Cache) Option {
+	return func(opts *options) {
+		opts.cache = cache
+	}
+}
@@ -0,0 +1,344 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+)
+
+// OutputFormat is a format a summary can be written in
+type OutputFormat string
+
+const (
+	// OutputJSON writes the summary as indented JSON
+	OutputJSON OutputFormat = "json"
+	// OutputYAML writes the summary as YAML
+	OutputYAML OutputFormat = "yaml"
+	// OutputTable writes a row per container with the current and recommended requests
+	OutputTable OutputFormat = "table"
+	// OutputWide is OutputTable with the current and recommended limits
+	OutputWide OutputFormat = "wide"
+	// OutputCSV writes the columns of OutputWide as CSV
+	OutputCSV OutputFormat = "csv"
+	// OutputMarkdown writes the columns of OutputTable as a markdown table
+	OutputMarkdown OutputFormat = "markdown"
+)
+
+// OutputFormats are all the supported output formats
+var OutputFormats = []OutputFormat{OutputTable, OutputWide, OutputYAML, OutputCSV, OutputMarkdown, OutputJSON}
+
+// SortBy is the order of the rows of a summary table
+type SortBy string
+
+const (
+	// SortByName sorts by namespace, kind, workload and container
+	SortByName SortBy = "name"
+	// SortByDelta sorts by the largest relative difference of cpu or memory, largest first
+	SortByDelta SortBy = "delta"
+	// SortByCPUDelta sorts by the absolute difference of cpu, largest first
+	SortByCPUDelta SortBy = "cpu-delta"
+	// SortByMemoryDelta sorts by the absolute difference of memory, largest first
+	SortByMemoryDelta SortBy = "memory-delta"
+	// SortByCPUOver sorts by how much the cpu request is above the recommendation, largest first
+	SortByCPUOver SortBy = "cpu-over"
+	// SortByCPUUnder sorts by how much the cpu request is below the recommendation, largest first
+	SortByCPUUnder SortBy = "cpu-under"
+	// SortByMemoryOver sorts by how much the memory request is above the recommendation, largest first
+	SortByMemoryOver SortBy = "memory-over"
+	// SortByMemoryUnder sorts by how much the memory request is below the recommendation, largest first
+	SortByMemoryUnder SortBy = "memory-under"
+)
+
+// SortOrders are all the supported sort orders
+var SortOrders = []SortBy{SortByName, SortByDelta, SortByCPUDelta, SortByMemoryDelta, SortByCPUOver, SortByCPUUnder, SortByMemoryOver, SortByMemoryUnder}
+
+// Row is the current and recommended resources of a single container
+type Row struct {
+	Namespace string
+	Kind      string
+	Workload  string
+	Container string
+	// Replicas is the number of pods of the workload
+	Replicas int32
+
+	CPURequest                resource.Quantity
+	CPURecommendation         resource.Quantity
+	CPULimit                  resource.Quantity
+	CPULimitRecommendation    resource.Quantity
+	MemoryRequest             resource.Quantity
+	MemoryRecommendation      resource.Quantity
+	MemoryLimit               resource.Quantity
+	MemoryLimitRecommendation resource.Quantity
+
+	// key of the workload in the summary
+	key string
+}
+
+// Rows returns a row for every container in the summary, sorted by name. The recommendations are the
+// recommended requests and limits when the summary has a limit strategy, and the target otherwise.
+func Rows(data Summary) []Row {
+	var rows []Row
+	for _, ns := range data.Namespaces {
+		for key, workload := range ns.Workloads {
+			for _, c := range workload.Containers {
+				recommendedRequests, recommendedLimits := c.Target, c.Target
+				if len(c.RecommendedRequests) > 0 || len(c.RecommendedLimits) > 0 {
+					recommendedRequests, recommendedLimits = c.RecommendedRequests, c.RecommendedLimits
+				}
+				rows = append(rows, Row{
+					Namespace:                 ns.Namespace,
+					Kind:                      workload.ControllerType,
+					Workload:                  workload.ControllerName,
+					Container:                 c.ContainerName,
+					Replicas:                  workload.Replicas,
+					CPURequest:                c.Requests[corev1.ResourceCPU],
+					CPURecommendation:         recommendedRequests[corev1.ResourceCPU],
+					CPULimit:                  c.Limits[corev1.ResourceCPU],
+					CPULimitRecommendation:    recommendedLimits[corev1.ResourceCPU],
+					MemoryRequest:             c.Requests[corev1.ResourceMemory],
+					MemoryRecommendation:      recommendedRequests[corev1.ResourceMemory],
+					MemoryLimit:               c.Limits[corev1.ResourceMemory],
+					MemoryLimitRecommendation: recommendedLimits[corev1.ResourceMemory],
+					key:                       key,
+				})
+			}
+		}
+	}
+	SortRows(rows, SortByName)
+	return rows
+}
+
+// CPUDelta is the recommended cpu request minus the current cpu request
+func (r Row) CPUDelta() resource.Quantity {
+	return delta(r.CPURequest, r.CPURecommendation)
+}
+
+// MemoryDelta is the recommended memory request minus the current memory request
+func (r Row) MemoryDelta() resource.Quantity {
+	return delta(r.MemoryRequest, r.MemoryRecommendation)
+}
+
+// RelativeDelta is the largest difference between the current and recommended cpu or memory
+// request, as a fraction of the recommendation
+func (r Row) RelativeDelta() float64 {
+	return math.Max(relativeDelta(r.CPURequest, r.CPURecommendation), relativeDelta(r.MemoryRequest, r.MemoryRecommendation))
+}
+
+func delta(current, recommended resource.Quantity) resource.Quantity {
+	d := recommended.DeepCopy()
+	d.Sub(current)
+	return d
+}
+
+func relativeDelta(current, recommended resource.Quantity) float64 {
+	if recommended.IsZero() {
+		return 0
+	}
+	d := delta(current, recommended)
+	return math.Abs(float64(d.MilliValue())) / float64(recommended.MilliValue())
+}
+
+// SortRows sorts the rows in place
+func SortRows(rows []Row, sortBy SortBy) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		switch sortBy {
+		case SortByDelta:
+			if di, dj := rows[i].RelativeDelta(), rows[j].RelativeDelta(); di != dj {
+				return di > dj
+			}
+		case SortByCPUDelta:
+			di, dj := rows[i].CPUDelta(), rows[j].CPUDelta()
+			if c := absMilli(di) - absMilli(dj); c != 0 {
+				return c > 0
+			}
+		case SortByMemoryDelta:
+			di, dj := rows[i].MemoryDelta(), rows[j].MemoryDelta()
+			if c := absMilli(di) - absMilli(dj); c != 0 {
+				return c > 0
+			}
+		case SortByCPUOver, SortByMemoryOver:
+			di, dj := rows[i].CPUDelta(), rows[j].CPUDelta()
+			if sortBy == SortByMemoryOver {
+				di, dj = rows[i].MemoryDelta(), rows[j].MemoryDelta()
+			}
+			if c := di.Cmp(dj); c != 0 {
+				return c < 0
+			}
+		case SortByCPUUnder, SortByMemoryUnder:
+			di, dj := rows[i].CPUDelta(), rows[j].CPUDelta()
+			if sortBy == SortByMemoryUnder {
+				di, dj = rows[i].MemoryDelta(), rows[j].MemoryDelta()
+			}
+			if c := di.Cmp(dj); c != 0 {
+				return c > 0
+			}
+		}
+		return rows[i].name() < rows[j].name()
+	})
+}
+
+func absMilli(q resource.Quantity) int64 {
+	if q.Sign() < 0 {
+		return -q.MilliValue()
+	}
+	return q.MilliValue()
+}
+
+func (r Row) name() string {
+	return strings.Join([]string{r.Namespace, r.Kind, r.Workload, r.Container}, "/")
+}
+
+// Write writes the summary to the writer in the given format. The sort order only applies to the
+// formats with a row per container.
+func Write(w io.Writer, data Summary, format OutputFormat, sortBy SortBy) error {
+	if !isValidSortBy(sortBy) {
+		return fmt.Errorf("unknown sort order %q, must be one of %v", sortBy, SortOrders)
+	}
+
+	switch format {
+	case OutputJSON:
+		encoded, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(encoded))
+		return err
+	case OutputYAML:
+		encoded, err := yaml.Marshal(data)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(encoded)
+		return err
+	case OutputTable, OutputWide, OutputCSV, OutputMarkdown:
+		rows := Rows(data)
+		SortRows(rows, sortBy)
+		if err := writeRows(w, rows, format); err != nil {
+			return err
+		}
+		if format == OutputTable || format == OutputWide {
+			return writeTotals(w, summaryTotals(data))
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, must be one of %v", format, OutputFormats)
+}
+
+func isValidSortBy(sortBy SortBy) bool {
+	for _, s := range SortOrders {
+		if s == sortBy {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	tableHeader = []string{"NAMESPACE", "KIND", "WORKLOAD", "CONTAINER", "CPU REQUEST", "CPU RECOMMENDED", "CPU DELTA", "MEMORY REQUEST", "MEMORY RECOMMENDED", "MEMORY DELTA"}
+	wideHeader  = append(append([]string{}, tableHeader...), "CPU LIMIT", "CPU LIMIT RECOMMENDED", "MEMORY LIMIT", "MEMORY LIMIT RECOMMENDED", "REPLICAS")
+)
+
+func writeRows(w io.Writer, rows []Row, format OutputFormat) error {
+	header := tableHeader
+	if format == OutputWide || format == OutputCSV {
+		header = wideHeader
+	}
+
+	records := make([][]string, 0, len(rows))
+	for _, r := range rows {
+		cpuDelta, memDelta := r.CPUDelta(), r.MemoryDelta()
+		record := []string{
+			r.Namespace, r.Kind, r.Workload, r.Container,
+			formatQuantity(r.CPURequest), formatQuantity(r.CPURecommendation), formatDelta(cpuDelta),
+			formatQuantity(r.MemoryRequest), formatQuantity(r.MemoryRecommendation), formatDelta(memDelta),
+		}
+		if len(header) == len(wideHeader) {
+			record = append(record,
+				formatQuantity(r.CPULimit), formatQuantity(r.CPULimitRecommendation),
+				formatQuantity(r.MemoryLimit), formatQuantity(r.MemoryLimitRecommendation),
+				strconv.Itoa(int(r.Replicas)),
+			)
+		}
+		records = append(records, record)
+	}
+
+	switch format {
+	case OutputCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(records); err != nil {
+			return err
+		}
+		cw.Flush()
+		return cw.Error()
+	case OutputMarkdown:
+		lines := []string{
+			"| " + strings.Join(header, " | ") + " |",
+			"|" + strings.Repeat(" --- |", len(header)),
+		}
+		for _, record := range records {
+			lines = append(lines, "| "+strings.Join(record, " | ")+" |")
+		}
+		_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+		return err
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		if _, err := fmt.Fprintln(tw, strings.Join(header, "\t")); err != nil {
+			return err
+		}
+		for _, record := range records {
+			if _, err := fmt.Fprintln(tw, strings.Join(record, "\t")); err != nil {
+				return err
+			}
+		}
+		return tw.Flush()
+	}
+}
+
+// writeTotals writes the total requests and recommendations of all the replicas below a table
+func writeTotals(w io.Writer, totals Totals) error {
+	_, err := fmt.Fprintf(w, "\nTotal requests of %d pods: cpu %s, memory %s\n", totals.Replicas, totals.CPU(), totals.Memory())
+	return err
+}
+
+// formatQuantity formats a quantity, with a dash for values that are not set
+func formatQuantity(q resource.Quantity) string {
+	if q.IsZero() {
+		return "-"
+	}
+	return q.String()
+}
+
+// formatDelta formats a difference with an explicit sign
+func formatDelta(q resource.Quantity) string {
+	if q.Sign() > 0 {
+		return "+" + q.String()
+	}
+	return q.String()
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOutputSummaryJSON = `{
+  "apiVersion": "goldilocks.fairwinds.com/v1",
+  "namespaces": {
+    "testing": {
+      "namespace": "testing",
+      "workloads": {
+        "web": {
+          "controllerName": "web",
+          "controllerType": "Deployment",
+          "replicas": 3,
+          "containers": {
+            "app": {
+              "containerName": "app",
+              "target": {"cpu": "100m", "memory": "128Mi"},
+              "requests": {"cpu": "500m", "memory": "128Mi"},
+              "limits": {"cpu": "1", "memory": "256Mi"}
+            }
+          }
+        },
+        "db": {
+          "controllerName": "db",
+          "controllerType": "StatefulSet",
+          "replicas": 2,
+          "limitStrategy": "no-cpu-limit,ratio:1.5",
+          "containers": {
+            "postgres": {
+              "containerName": "postgres",
+              "target": {"cpu": "250m", "memory": "1Gi"},
+              "recommendedRequests": {"cpu": "250m", "memory": "1Gi"},
+              "recommendedLimits": {"memory": "1536Mi"},
+              "requests": {"cpu": "200m", "memory": "512Mi"}
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+func testOutputSummary(t *testing.T) Summary {
+	var data Summary
+	require.NoError(t, json.Unmarshal([]byte(testOutputSummaryJSON), &data))
+	return data
+}
+
+func TestRows(t *testing.T) {
+	rows := Rows(testOutputSummary(t))
+	require.Len(t, rows, 2)
+
+	assert.Equal(t, "Deployment", rows[0].Kind)
+	cpuDelta, memDelta := rows[0].CPUDelta(), rows[0].MemoryDelta()
+	assert.Equal(t, "-400m", cpuDelta.String())
+	assert.Equal(t, "0", memDelta.String())
+	assert.InDelta(t, 4.0, rows[0].RelativeDelta(), 0.001)
+
+	assert.Equal(t, "StatefulSet", rows[1].Kind)
+	assert.Equal(t, "1536Mi", rows[1].MemoryLimitRecommendation.String())
+	assert.True(t, rows[1].CPULimitRecommendation.IsZero())
+	memDelta = rows[1].MemoryDelta()
+	assert.Equal(t, "512Mi", memDelta.String())
+
+	SortRows(rows, SortByMemoryDelta)
+	assert.Equal(t, "db", rows[0].Workload)
+	SortRows(rows, SortByDelta)
+	assert.Equal(t, "web", rows[0].Workload)
+}
+
+func TestWrite(t *testing.T) {
+	data := testOutputSummary(t)
+
+	var table bytes.Buffer
+	require.NoError(t, Write(&table, data, OutputTable, SortByName))
+	assert.Equal(t, `NAMESPACE   KIND          WORKLOAD   CONTAINER   CPU REQUEST   CPU RECOMMENDED   CPU DELTA   MEMORY REQUEST   MEMORY RECOMMENDED   MEMORY DELTA
+testing     Deployment    web        app         500m          100m              -400m       128Mi            128Mi                0
+testing     StatefulSet   db         postgres    200m          250m              +50m        512Mi            1Gi                  +512Mi
+
+Total requests of 5 pods: cpu 1900m -> 800m (-1100m), memory 1408Mi -> 2432Mi (+1Gi)
+`, table.String())
+
+	var markdown bytes.Buffer
+	require.NoError(t, Write(&markdown, data, OutputMarkdown, SortByCPUDelta))
+	assert.Equal(t, `| NAMESPACE | KIND | WORKLOAD | CONTAINER | CPU REQUEST | CPU RECOMMENDED | CPU DELTA | MEMORY REQUEST | MEMORY RECOMMENDED | MEMORY DELTA |
+| --- | --- | --- | --- | --- | --- | --- | --- | --- | --- |
+| testing | Deployment | web | app | 500m | 100m | -400m | 128Mi | 128Mi | 0 |
+| testing | StatefulSet | db | postgres | 200m | 250m | +50m | 512Mi | 1Gi | +512Mi |
+`, markdown.String())
+
+	var csv bytes.Buffer
+	require.NoError(t, Write(&csv, data, OutputCSV, SortByName))
+	assert.Contains(t, csv.String(), "NAMESPACE,KIND,WORKLOAD,CONTAINER,CPU REQUEST,CPU RECOMMENDED,CPU DELTA,MEMORY REQUEST,MEMORY RECOMMENDED,MEMORY DELTA,CPU LIMIT,CPU LIMIT RECOMMENDED,MEMORY LIMIT,MEMORY LIMIT RECOMMENDED,REPLICAS\n")
+	assert.Contains(t, csv.String(), "testing,StatefulSet,db,postgres,200m,250m,+50m,512Mi,1Gi,+512Mi,-,-,-,1536Mi,2\n")
+
+	var pretty bytes.Buffer
+	require.NoError(t, Write(&pretty, data, OutputJSON, SortByName))
+	assert.Contains(t, pretty.String(), "{\n  \"apiVersion\": \"goldilocks.fairwinds.com/v1\",\n  \"namespaces\": {")
+
+	var yaml bytes.Buffer
+	require.NoError(t, Write(&yaml, data, OutputYAML, SortByName))
+	assert.Contains(t, yaml.String(), "limitStrategy: no-cpu-limit,ratio:1.5")
+
+	assert.Error(t, Write(&table, data, OutputFormat("bogus"), SortByName))
+	assert.Error(t, Write(&table, data, OutputTable, SortBy("bogus")))
+}
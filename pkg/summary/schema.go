@@ -0,0 +1,156 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// schemaEnums are the known values of the string types of the summary
+var schemaEnums = map[reflect.Type][]string{
+	reflect.TypeOf(WorkloadStatus("")): {
+		string(WorkloadStatusOK),
+		string(WorkloadStatusNoRecommendationYet),
+		string(WorkloadStatusWorkloadNotFound),
+		string(WorkloadStatusPodSpecUnparsable),
+		string(WorkloadStatusContainerMissing),
+	},
+	reflect.TypeOf(ContainerType("")): {
+		string(ContainerTypeContainer),
+		string(ContainerTypeSidecar),
+		string(ContainerTypeInit),
+	},
+	reflect.TypeOf(CostPeriod("")): {
+		string(CostPerHour),
+		string(CostPerDay),
+		string(CostPerMonth),
+	},
+	reflect.TypeOf(ResourceStatus("")): {
+		string(StatusEqual),
+		string(StatusLessThan),
+		string(StatusGreaterThan),
+		string(StatusNotSet),
+	},
+}
+
+var (
+	quantityType      = reflect.TypeOf(resource.Quantity{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// JSONSchema returns the JSON Schema of the Summary, generated from its types
+func JSONSchema() ([]byte, error) {
+	g := schemaGenerator{definitions: map[string]any{}, refPrefix: "#/$defs/"}
+	root := g.structSchema(reflect.TypeOf(Summary{}))
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = "Goldilocks summary"
+	root["$defs"] = g.definitions
+	return json.MarshalIndent(root, "", "  ")
+}
+
+// Schemas returns the JSON Schemas of the types of the values and of the structs they refer to, by type name.
+// References to other structs start with refPrefix, e.g. #/components/schemas/ in an OpenAPI document.
+func Schemas(refPrefix string, values ...any) map[string]any {
+	g := schemaGenerator{definitions: map[string]any{}, refPrefix: refPrefix}
+	for _, value := range values {
+		g.schema(reflect.TypeOf(value))
+	}
+	return g.definitions
+}
+
+type schemaGenerator struct {
+	definitions map[string]any
+	refPrefix   string
+}
+
+func (g schemaGenerator) schema(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Ptr {
+		return g.schema(t.Elem())
+	}
+	if t == quantityType {
+		return map[string]any{"type": "string", "description": "a Kubernetes quantity, e.g. 100m or 128Mi"}
+	}
+	if enum, ok := schemaEnums[t]; ok {
+		return map[string]any{"type": "string", "enum": enum}
+	}
+	if t.Implements(textMarshalerType) {
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	// nil slices and maps are written as null
+	case reflect.Slice:
+		return map[string]any{"type": []string{"array", "null"}, "items": g.schema(t.Elem())}
+	case reflect.Array:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": []string{"object", "null"}, "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if _, ok := g.definitions[t.Name()]; !ok {
+			// reserve the name first so recursive types terminate
+			g.definitions[t.Name()] = nil
+			g.definitions[t.Name()] = g.structSchema(t)
+		}
+		return map[string]any{"$ref": g.refPrefix + t.Name()}
+	}
+	return map[string]any{}
+}
+
+// structSchema returns the schema of the exported fields of a struct, following their json tags.
+// Fields without omitempty or omitzero are required.
+func (g schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, flags, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.schema(field.Type)
+		if !strings.Contains(flags, "omitempty") && !strings.Contains(flags, "omitzero") {
+			required = append(required, name)
+		}
+	}
+	if t == reflect.TypeOf(Summary{}) {
+		properties["apiVersion"] = map[string]any{"type": "string", "const": APIVersion}
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
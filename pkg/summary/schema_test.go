@@ -0,0 +1,82 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSchema struct {
+	Properties map[string]json.RawMessage `json:"properties"`
+	Required   []string                   `json:"required"`
+	Defs       map[string]testSchema      `json:"$defs"`
+}
+
+func TestJSONSchema(t *testing.T) {
+	encoded, err := JSONSchema()
+	require.NoError(t, err)
+
+	var schema testSchema
+	require.NoError(t, json.Unmarshal(encoded, &schema))
+	assert.ElementsMatch(t, []string{"apiVersion", "namespaces"}, schema.Required)
+	assert.JSONEq(t, `{"type": "string", "const": "goldilocks.fairwinds.com/v1"}`, string(schema.Properties["apiVersion"]))
+
+	assert.NotContains(t, schema.Defs["NamespaceSummary"].Properties, "IsOnlyNamespace")
+	assert.NotContains(t, schema.Defs["NamespaceSummary"].Properties, "BasePath")
+	assert.NotContains(t, schema.Defs["ContainerSummary"].Properties, "ContainerCostInt")
+	assert.NotContains(t, schema.Defs["ContainerSummary"].Required, "containerType", "optional fields are not required")
+	assert.JSONEq(t, `{"type": "string", "enum": ["OK", "NoRecommendationYet", "WorkloadNotFound", "PodSpecUnparsable", "ContainerMissing"]}`,
+		string(schema.Defs["WorkloadSummary"].Properties["status"]))
+	assert.JSONEq(t, `{"type": "string"}`, string(schema.Defs["WorkloadSummary"].Properties["limitStrategy"]))
+
+	// every field written for a summary is in the schema
+	data, err := json.Marshal(testSummary)
+	require.NoError(t, err)
+	var summary struct {
+		Namespaces map[string]struct {
+			Workloads map[string]map[string]json.RawMessage `json:"workloads"`
+		} `json:"namespaces"`
+	}
+	require.NoError(t, json.Unmarshal(data, &summary))
+	for _, ns := range summary.Namespaces {
+		for _, workload := range ns.Workloads {
+			for field := range workload {
+				assert.Contains(t, schema.Defs["WorkloadSummary"].Properties, field)
+			}
+			var containers map[string]map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(workload["containers"], &containers))
+			for _, container := range containers {
+				for field := range container {
+					assert.Contains(t, schema.Defs["ContainerSummary"].Properties, field)
+				}
+			}
+		}
+	}
+}
+
+func TestSchemas(t *testing.T) {
+	schemas := Schemas("#/components/schemas/", WorkloadSummary{})
+	assert.Contains(t, schemas, "WorkloadSummary")
+	assert.Contains(t, schemas, "ContainerSummary")
+	assert.NotContains(t, schemas, "Summary")
+
+	encoded, err := json.Marshal(schemas["WorkloadSummary"])
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), `"$ref":"#/components/schemas/Totals"`)
+}
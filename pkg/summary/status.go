@@ -0,0 +1,74 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/fairwindsops/goldilocks/pkg/utils"
+)
+
+// ResourceStatus is the result of comparing a current resource value to its recommendation
+type ResourceStatus string
+
+const (
+	// StatusEqual means the current value is equal to the recommendation, or within the tolerance of it
+	StatusEqual ResourceStatus = "equal"
+	// StatusLessThan means the current value is below the recommendation
+	StatusLessThan ResourceStatus = "less than"
+	// StatusGreaterThan means the current value is above the recommendation
+	StatusGreaterThan ResourceStatus = "greater than"
+	// StatusNotSet means there is no current value
+	StatusNotSet ResourceStatus = "not set"
+)
+
+// compareResourceLists compares every recommended resource with the current value
+func compareResourceLists(tolerance utils.Tolerance, current, recommended corev1.ResourceList) map[corev1.ResourceName]ResourceStatus {
+	if len(recommended) == 0 {
+		return nil
+	}
+
+	statuses := map[corev1.ResourceName]ResourceStatus{}
+	for name, recommendation := range recommended {
+		existing, ok := current[name]
+		if !ok || existing.IsZero() {
+			statuses[name] = StatusNotSet
+			continue
+		}
+		switch tolerance.Compare(name, existing, recommendation) {
+		case 0:
+			statuses[name] = StatusEqual
+		case -1:
+			statuses[name] = StatusLessThan
+		default:
+			statuses[name] = StatusGreaterThan
+		}
+	}
+	return statuses
+}
+
+// IsWithinTolerance returns true if the current request of every recommended
+// resource is equal to the target, or within the tolerance of it
+func (c ContainerSummary) IsWithinTolerance() bool {
+	if len(c.RequestStatus) == 0 {
+		return false
+	}
+	for _, status := range c.RequestStatus {
+		if status != StatusEqual {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/fairwindsops/goldilocks/pkg/utils"
+)
+
+func Test_compareResourceLists(t *testing.T) {
+	tolerance, err := utils.ParseTolerance("cpu=10%,memory=10%")
+	assert.NoError(t, err)
+
+	current := v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("95m"),
+		v1.ResourceMemory: resource.MustParse("50Mi"),
+	}
+	got := compareResourceLists(tolerance, current, targetResources)
+	assert.Equal(t, map[v1.ResourceName]ResourceStatus{
+		v1.ResourceCPU:    StatusEqual,
+		v1.ResourceMemory: StatusLessThan,
+	}, got)
+
+	got = compareResourceLists(utils.Tolerance{}, current, targetResources)
+	assert.Equal(t, StatusLessThan, got[v1.ResourceCPU])
+
+	got = compareResourceLists(tolerance, nil, targetResources)
+	assert.Equal(t, StatusNotSet, got[v1.ResourceCPU])
+
+	assert.Nil(t, compareResourceLists(tolerance, current, nil))
+}
+
+func Test_IsWithinTolerance(t *testing.T) {
+	assert.True(t, ContainerSummary{RequestStatus: equalStatus}.IsWithinTolerance())
+	assert.False(t, ContainerSummary{}.IsWithinTolerance())
+	assert.False(t, ContainerSummary{RequestStatus: map[v1.ResourceName]ResourceStatus{
+		v1.ResourceCPU:    StatusEqual,
+		v1.ResourceMemory: StatusGreaterThan,
+	}}.IsWithinTolerance())
+}
@@ -16,6 +16,8 @@ package summary
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"strings"
 
 	controllerUtils "github.com/fairwindsops/controller-utils/pkg/controller"
@@ -35,42 +37,113 @@ const (
 	namespaceAllNamespaces = ""
 )
 
+// APIVersion is the version of the Summary schema. It changes whenever a field is
+// removed or changes its meaning, new optional fields are added without a new version.
+const APIVersion = "goldilocks.fairwinds.com/v1"
+
 // Summary is for storing a summary of recommendation data by namespace/controller type/container
 type Summary struct {
-	Namespaces map[string]namespaceSummary
+	// APIVersion is the version of the schema, see APIVersion
+	APIVersion string `json:"apiVersion"`
+	// Namespaces are the namespaces with VPAs, by name
+	Namespaces map[string]NamespaceSummary `json:"namespaces"`
+	// Warnings are the problems with VPAs that could not be summarized completely
+	Warnings []Warning `json:"warnings,omitempty"`
+	// Totals are the requests and recommendations of all the namespaces
+	Totals Totals `json:"totals,omitzero"`
+	// Currency and CostPeriod are the currency and period of the costs, when the summary has a cost model
+	Currency   string     `json:"currency,omitempty"`
+	CostPeriod CostPeriod `json:"costPeriod,omitempty"`
+}
+
+// NamespaceSummary is the summary of the workloads of a namespace
+type NamespaceSummary struct {
+	// Namespace is the name of the namespace
+	Namespace string `json:"namespace"`
+	// Workloads are the workloads with a VPA, by the key of the workload, see WorkloadKey
+	Workloads map[string]WorkloadSummary `json:"workloads"`
+	// Totals are the requests and recommendations of all the workloads of the namespace
+	Totals Totals `json:"totals,omitzero"`
+
+	// presentation only, used by the dashboard
+	BasePath        string `json:"-"`
+	IsOnlyNamespace bool   `json:"-"`
 }
 
-type namespaceSummary struct {
-	Namespace       string                     `json:"namespace"`
-	Workloads       map[string]workloadSummary `json:"workloads"`
-	BasePath        string
-	IsOnlyNamespace bool
+// WorkloadSummary is the summary of the containers of a workload targeted by a VPA
+type WorkloadSummary struct {
+	// APIVersion is the API version of the workload, e.g. apps/v1
+	APIVersion string `json:"apiVersion,omitempty"`
+	// ControllerName is the name of the workload
+	ControllerName string `json:"controllerName"`
+	// ControllerType is the kind of the workload, e.g. Deployment
+	ControllerType string `json:"controllerType"`
+	// Containers are the containers with a recommendation, by container name
+	Containers map[string]ContainerSummary `json:"containers"`
+	// Replicas is the number of pods of the workload, the number of nodes for a DaemonSet
+	Replicas int32 `json:"replicas,omitempty"`
+	// Totals are the requests and recommendations of all the replicas of the workload
+	Totals Totals `json:"totals,omitzero"`
+	// Price is the price of the nodes the pods of the workload run on, averaged over the pods,
+	// when the summary has a cost model
+	Price *Price `json:"price,omitempty"`
+	// Status is whether the recommendations of the workload could be summarized
+	Status WorkloadStatus `json:"status,omitempty"`
+	// Headroom is the percentage added on top of the VPA target
+	Headroom utils.Headroom `json:"headroom,omitempty"`
+	// LimitStrategy is the strategy of the recommended requests and limits
+	LimitStrategy utils.LimitStrategy `json:"limitStrategy,omitzero"`
+
+	// presentation only, used by the dashboard
+	BasePath string `json:"-"`
 }
 
-type workloadSummary struct {
-	ControllerName string                      `json:"controllerName"`
-	ControllerType string                      `json:"controllerType"`
-	Containers     map[string]ContainerSummary `json:"containers"`
-	BasePath       string
+// WorkloadKey returns the key of a workload in NamespaceSummary.Workloads, e.g. apps/v1/Deployment/web.
+// Workloads of different kinds or API versions can share a name in a namespace.
+func WorkloadKey(apiVersion, kind, name string) string {
+	return strings.Join([]string{apiVersion, kind, name}, "/")
 }
 
+// Key returns the key of the workload in NamespaceSummary.Workloads
+func (w WorkloadSummary) Key() string {
+	return WorkloadKey(w.APIVersion, w.ControllerType, w.ControllerName)
+}
+
+// ContainerSummary is the recommendation and the current requests and limits of a container
 type ContainerSummary struct {
-	ContainerName string `json:"containerName"`
+	ContainerName string        `json:"containerName"`
+	ContainerType ContainerType `json:"containerType,omitempty"`
 
 	// recommendations
-	LowerBound        corev1.ResourceList `json:"lowerBound"`
-	UpperBound        corev1.ResourceList `json:"upperBound"`
-	Target            corev1.ResourceList `json:"target"`
-	UncappedTarget    corev1.ResourceList `json:"uncappedTarget"`
-	Limits            corev1.ResourceList `json:"limits"`
-	Requests          corev1.ResourceList `json:"requests"`
-	BasePath          string
-	ContainerCost     float64
-	GuaranteedCost    float64
-	BurstableCost     float64
-	ContainerCostInt  int
-	GuaranteedCostInt int
-	BurstableCostInt  int
+	LowerBound     corev1.ResourceList `json:"lowerBound"`
+	UpperBound     corev1.ResourceList `json:"upperBound"`
+	Target         corev1.ResourceList `json:"target"`
+	RawTarget      corev1.ResourceList `json:"rawTarget,omitempty"`
+	UncappedTarget corev1.ResourceList `json:"uncappedTarget"`
+	Limits         corev1.ResourceList `json:"limits"`
+	Requests       corev1.ResourceList `json:"requests"`
+
+	// requests and limits derived from the recommendation by the limit strategy
+	RecommendedRequests corev1.ResourceList `json:"recommendedRequests,omitempty"`
+	RecommendedLimits   corev1.ResourceList `json:"recommendedLimits,omitempty"`
+
+	// comparison of the current requests and limits to the target, or to the
+	// recommended requests and limits when there is a limit strategy
+	RequestStatus map[corev1.ResourceName]ResourceStatus `json:"requestStatus,omitempty"`
+	LimitStatus   map[corev1.ResourceName]ResourceStatus `json:"limitStatus,omitempty"`
+
+	// Costs are the costs of all the replicas in the currency and period of the summary,
+	// when the summary has a cost model
+	Costs *ContainerCosts `json:"costs,omitempty"`
+
+	// deprecated, use Costs: hourly cost of the current resources of a single replica, and
+	// the absolute difference to it of the guaranteed and burstable recommendations
+	ContainerCost  float64 `json:"containerCost,omitempty"`
+	GuaranteedCost float64 `json:"guaranteedCost,omitempty"`
+	BurstableCost  float64 `json:"burstableCost,omitempty"`
+
+	// presentation only, used by the dashboard
+	BasePath string `json:"-"`
 }
 
 // Summarizer represents a source of generating a summary of VPAs
@@ -80,7 +153,7 @@ type Summarizer struct {
 	// cached list of vpas
 	vpas []vpav1.VerticalPodAutoscaler
 
-	// cached map of vpa name -> workload
+	// cached map of vpa namespace/name -> workload
 	workloadForVPANamed map[string]*controllerUtils.Workload
 }
 
@@ -110,18 +183,30 @@ func NewSummarizerForVPAs(vpas []vpav1.VerticalPodAutoscaler, setters ...Option)
 func (s Summarizer) GetSummary() (Summary, error) {
 	// blank summary
 	summary := Summary{
-		Namespaces: map[string]namespaceSummary{},
+		APIVersion: APIVersion,
+		Namespaces: map[string]NamespaceSummary{},
+	}
+
+	if s.costModel != nil {
+		summary.Currency = s.costModel.Currency()
+		summary.CostPeriod = s.costModel.Period
 	}
 
 	// if the summarizer is filtering for a single namespace,
 	// then add that namespace by default to the blank summary
 	if s.namespace != namespaceAllNamespaces {
-		summary.Namespaces[s.namespace] = namespaceSummary{
+		summary.Namespaces[s.namespace] = NamespaceSummary{
 			Namespace: s.namespace,
-			Workloads: map[string]workloadSummary{},
+			Workloads: map[string]WorkloadSummary{},
 		}
 	}
 
+	// namespaces, looked up once per summary
+	namespaces := map[string]*corev1.Namespace{}
+
+	// nodes, looked up once per summary for the price of the nodes the pods run on
+	nodes := map[string]*corev1.Node{}
+
 	// cached vpas and workloads
 	if s.vpas == nil || s.workloadForVPANamed == nil {
 		err := s.Update()
@@ -135,46 +220,82 @@ func (s Summarizer) GetSummary() (Summary, error) {
 		return summary, nil
 	}
 
-	for _, vpa := range s.vpas {
+	// namespace/workload key -> name of the vpa the workload is summarized from
+	summarizedBy := map[string]string{}
+
+	for _, vpa := range preferredVPAsFirst(s.vpas) {
 		klog.V(8).Infof("Analyzing vpa: %v", vpa.Name)
 
-		// get or create the namespaceSummary for this VPA's namespace
+		if !s.matchesFilters(vpa, namespaces) {
+			klog.V(8).Infof("Skipping vpa %v, it does not match the filters", vpa.Name)
+			continue
+		}
+
+		// get or create the NamespaceSummary for this VPA's namespace
 		namespace := vpa.Namespace
-		var nsSummary namespaceSummary
+		var nsSummary NamespaceSummary
 		if val, ok := summary.Namespaces[namespace]; ok {
 			nsSummary = val
 		} else {
-			nsSummary = namespaceSummary{
+			nsSummary = NamespaceSummary{
 				Namespace: namespace,
-				Workloads: map[string]workloadSummary{},
+				Workloads: map[string]WorkloadSummary{},
 			}
 			summary.Namespaces[namespace] = nsSummary
 		}
 
-		wSummary := workloadSummary{
+		wSummary := WorkloadSummary{
+			APIVersion:     vpa.Spec.TargetRef.APIVersion,
 			ControllerName: vpa.Spec.TargetRef.Name,
 			ControllerType: vpa.Spec.TargetRef.Kind,
 			Containers:     map[string]ContainerSummary{},
 		}
 
-		workload, ok := s.workloadForVPANamed[vpa.Name]
+		// only the first of several VPAs targeting the same workload is summarized
+		if other, ok := summarizedBy[namespace+"/"+wSummary.Key()]; ok {
+			klog.V(2).Infof("Skipping vpa %s/%s, %s is already summarized from vpa %s", namespace, vpa.Name, wSummary.Key(), other)
+			continue
+		}
+		summarizedBy[namespace+"/"+wSummary.Key()] = vpa.Name
+
+		// add a workload that can't be summarized with its status and a warning
+		addWarning := func(status WorkloadStatus, container, message string) {
+			klog.V(2).Infof("%s: %s", vpa.Name, message)
+			if wSummary.Status == WorkloadStatusOK || wSummary.Status == "" {
+				wSummary.Status = status
+			}
+			summary.Warnings = append(summary.Warnings, Warning{
+				Type:      status,
+				Namespace: namespace,
+				VPA:       vpa.Name,
+				Kind:      wSummary.ControllerType,
+				Workload:  wSummary.ControllerName,
+				Container: container,
+				Message:   message,
+			})
+		}
+
+		workload, ok := s.workloadForVPANamed[vpaKey(vpa)]
 		if !ok {
-			klog.Errorf("no matching Workloads found for VPA/%s", vpa.Name)
+			addWarning(WorkloadStatusWorkloadNotFound, "", fmt.Sprintf("no matching workload found for VPA %s", vpa.Name))
+			nsSummary.Workloads[wSummary.Key()] = wSummary
 			continue
 		}
 
-		if vpa.Status.Recommendation == nil {
-			klog.V(2).Infof("Empty status on %v", wSummary.ControllerName)
-			nsSummary.Workloads[wSummary.ControllerName] = wSummary
-			summary.Namespaces[nsSummary.Namespace] = nsSummary
+		if vpa.Status.Recommendation == nil || len(vpa.Status.Recommendation.ContainerRecommendations) <= 0 {
+			addWarning(WorkloadStatusNoRecommendationYet, "", fmt.Sprintf("VPA %s has no recommendations yet", vpa.Name))
+			nsSummary.Workloads[wSummary.Key()] = wSummary
 			continue
 		}
-		if len(vpa.Status.Recommendation.ContainerRecommendations) <= 0 {
-			klog.V(2).Infof("No container recommendations found in the %v vpa.", wSummary.ControllerName)
-			nsSummary.Workloads[wSummary.ControllerName] = wSummary
-			summary.Namespaces[nsSummary.Namespace] = nsSummary
+
+		workloadPodSpec, err := getPodSpec(workload)
+		if err != nil {
+			addWarning(WorkloadStatusPodSpecUnparsable, "", err.Error())
+			nsSummary.Workloads[wSummary.Key()] = wSummary
 			continue
 		}
+		wSummary.Status = WorkloadStatusOK
+		wSummary.Replicas = getReplicas(workload)
 
 		// get the full set of excluded containers for this workload
 		excludedContainers := (sets.Set[string]{}).Union(s.excludedContainers)
@@ -182,6 +303,25 @@ func (s Summarizer) GetSummary() (Summary, error) {
 			excludedContainers.Insert(strings.Split(val, ",")...)
 		}
 
+		// the safety margin added on top of the target
+		headroom := s.getHeadroom(workload, s.getNamespace(namespace, namespaces).GetAnnotations())
+		if len(headroom) > 0 {
+			wSummary.Headroom = headroom
+		}
+
+		// how the recommended requests and limits are derived from the recommendation
+		limitStrategy := s.getLimitStrategy(namespace, s.getNamespace(namespace, namespaces).GetAnnotations())
+		wSummary.LimitStrategy = limitStrategy
+
+		// the price of the nodes the pods run on
+		var price Price
+		if s.costModel != nil {
+			price = s.costModel.workloadPrice(workload.Pods, func(name string) map[string]string {
+				return s.getNode(name, nodes).GetLabels()
+			})
+			wSummary.Price = &price
+		}
+
 	CONTAINER_REC_LOOP:
 		for _, containerRecommendation := range vpa.Status.Recommendation.ContainerRecommendations {
 			if excludedContainers.Has(containerRecommendation.ContainerName) {
@@ -190,64 +330,185 @@ func (s Summarizer) GetSummary() (Summary, error) {
 			}
 
 			var cSummary ContainerSummary
-			workloadPodSpecUnstructured, workloadPodSpecFound, err := unstructured.NestedMap(workload.TopController.UnstructuredContent(), "spec", "template", "spec")
-			if err != nil {
-				klog.Errorf("unable to parse spec.template.spec from unstructured workload. Namespace: '%s', Kind: '%s', Name: '%s'", workload.TopController.GetNamespace(), workload.TopController.GetKind(), workload.TopController.GetName())
-				continue CONTAINER_REC_LOOP
-			}
-
-			var workloadPodSpec corev1.PodSpec
-			if workloadPodSpecFound {
-				err = runtime.DefaultUnstructuredConverter.FromUnstructured(workloadPodSpecUnstructured, &workloadPodSpec)
-				if err != nil {
-					klog.Errorf("unable to convert unstructured pod spec to PodSpec struct. Namespace: '%s', Kind: '%s', Name: '%s'", workload.TopController.GetNamespace(), workload.TopController.GetKind(), workload.TopController.GetName())
-					continue CONTAINER_REC_LOOP
-				}
-			} else {
-				// fallback to the workload's pod spec
-				if workload.PodSpec == nil {
-					klog.Errorf("no pod spec found for workload. Namespace: '%s', Kind: '%s', Name: '%s'", workload.TopController.GetNamespace(), workload.TopController.GetKind(), workload.TopController.GetName())
-					continue CONTAINER_REC_LOOP
-				}
-				workloadPodSpec = *workload.PodSpec
-			}
-
-			for _, c := range workloadPodSpec.Containers {
+			for _, c := range podContainers(workloadPodSpec) {
 				// find the matching container on the workload
 				if c.Name == containerRecommendation.ContainerName {
 					cSummary = ContainerSummary{
 						ContainerName:  containerRecommendation.ContainerName,
-						UpperBound:     utils.FormatResourceList(containerRecommendation.UpperBound),
-						LowerBound:     utils.FormatResourceList(containerRecommendation.LowerBound),
-						Target:         utils.FormatResourceList(containerRecommendation.Target),
-						UncappedTarget: utils.FormatResourceList(containerRecommendation.UncappedTarget),
+						ContainerType:  c.containerType,
+						UpperBound:     s.rounding.Apply(utils.FormatResourceList(containerRecommendation.UpperBound)),
+						LowerBound:     s.rounding.Apply(utils.FormatResourceList(containerRecommendation.LowerBound)),
+						Target:         s.rounding.Apply(utils.FormatResourceList(headroom.Apply(utils.FormatResourceList(containerRecommendation.Target)))),
+						UncappedTarget: s.rounding.Apply(utils.FormatResourceList(containerRecommendation.UncappedTarget)),
 						Limits:         utils.FormatResourceList(c.Resources.Limits),
 						Requests:       utils.FormatResourceList(c.Resources.Requests),
 					}
+					if len(headroom) > 0 {
+						cSummary.RawTarget = utils.FormatResourceList(containerRecommendation.Target.DeepCopy())
+					}
+					if limitStrategy.IsZero() {
+						cSummary.RequestStatus = compareResourceLists(s.tolerance, cSummary.Requests, cSummary.Target)
+						cSummary.LimitStatus = compareResourceLists(s.tolerance, cSummary.Limits, cSummary.Target)
+					} else {
+						cSummary.RecommendedRequests, cSummary.RecommendedLimits = limitStrategy.Recommend(cSummary.Target, cSummary.LowerBound, cSummary.UpperBound)
+						cSummary.RequestStatus = compareResourceLists(s.tolerance, cSummary.Requests, cSummary.RecommendedRequests)
+						cSummary.LimitStatus = compareResourceLists(s.tolerance, cSummary.Limits, cSummary.RecommendedLimits)
+					}
+					if s.costModel != nil {
+						s.costModel.setCosts(&cSummary, price, wSummary.Replicas)
+					}
 					klog.V(6).Infof("Resources for %s/%s/%s: Requests: %v Limits: %v", wSummary.ControllerType, wSummary.ControllerName, c.Name, cSummary.Requests, cSummary.Limits)
+					if s.minDifference > 0 && cSummary.difference() <= s.minDifference {
+						klog.V(3).Infof("Skipping %s/%s/%s, requests are within %v%% of the recommendation", wSummary.ControllerType, wSummary.ControllerName, c.Name, s.minDifference)
+						continue CONTAINER_REC_LOOP
+					}
 					wSummary.Containers[cSummary.ContainerName] = cSummary
 					continue CONTAINER_REC_LOOP
 				}
 			}
+			addWarning(WorkloadStatusContainerMissing, containerRecommendation.ContainerName, fmt.Sprintf("container %s of the recommendation is not in the pod spec of %s %s", containerRecommendation.ContainerName, wSummary.ControllerType, wSummary.ControllerName))
+		}
+		if s.minDifference > 0 && len(wSummary.Containers) == 0 {
+			continue
 		}
 		// update summary maps
-		nsSummary.Workloads[wSummary.ControllerName] = wSummary
+		nsSummary.Workloads[wSummary.Key()] = wSummary
 		summary.Namespaces[nsSummary.Namespace] = nsSummary
 	}
 
-	// Indicate if this is the only namespace we are returning. This allows us
-	// to manipulate the summary on the dashboard
-	if len(summary.Namespaces) == 1 {
-		for namespaceName, namespace := range summary.Namespaces {
-			klog.V(3).Infof("setting ns %s as the only namespace", namespaceName)
-			namespace.IsOnlyNamespace = true
-			summary.Namespaces[namespaceName] = namespace
+	// drop the namespaces that only had filtered workloads
+	if s.hasFilters() {
+		for name, ns := range summary.Namespaces {
+			if len(ns.Workloads) == 0 && name != s.namespace {
+				delete(summary.Namespaces, name)
+			}
 		}
 	}
 
+	setTotals(&summary)
+
+	setIsOnlyNamespace(&summary)
+
 	return summary, nil
 }
 
+// getHeadroom returns the headroom for the workload. The workload annotation takes precedence
+// over the namespace annotation, which takes precedence over the Summarizer's headroom.
+func (s Summarizer) getHeadroom(workload *controllerUtils.Workload, namespaceAnnotations map[string]string) utils.Headroom {
+	if val, exists := workload.TopController.GetAnnotations()[utils.RecommendationHeadroomAnnotation]; exists {
+		headroom, err := utils.ParseHeadroom(val)
+		if err == nil {
+			return headroom
+		}
+		klog.Errorf("invalid %s annotation on %s/%s/%s: %v", utils.RecommendationHeadroomAnnotation, workload.TopController.GetNamespace(), workload.TopController.GetKind(), workload.TopController.GetName(), err)
+	}
+
+	if val, exists := namespaceAnnotations[utils.RecommendationHeadroomAnnotation]; exists {
+		headroom, err := utils.ParseHeadroom(val)
+		if err == nil {
+			return headroom
+		}
+		klog.Errorf("invalid %s annotation on namespace %s: %v", utils.RecommendationHeadroomAnnotation, workload.TopController.GetNamespace(), err)
+	}
+	return s.headroom
+}
+
+// getLimitStrategy returns the limit strategy for the namespace. The namespace annotation
+// takes precedence over the Summarizer's limit strategy.
+func (s Summarizer) getLimitStrategy(namespace string, namespaceAnnotations map[string]string) utils.LimitStrategy {
+	if val, exists := namespaceAnnotations[utils.LimitStrategyAnnotation]; exists {
+		strategy, err := utils.ParseLimitStrategy(val)
+		if err == nil {
+			return strategy
+		}
+		klog.Errorf("invalid %s annotation on namespace %s: %v", utils.LimitStrategyAnnotation, namespace, err)
+	}
+	return s.limitStrategy
+}
+
+// setIsOnlyNamespace indicates if a namespace is the only one of the summary. This allows us
+// to manipulate the summary on the dashboard
+func setIsOnlyNamespace(data *Summary) {
+	for namespaceName, namespace := range data.Namespaces {
+		namespace.IsOnlyNamespace = len(data.Namespaces) == 1
+		if namespace.IsOnlyNamespace {
+			klog.V(3).Infof("setting ns %s as the only namespace", namespaceName)
+		}
+		data.Namespaces[namespaceName] = namespace
+	}
+}
+
+// getPodSpec returns the pod spec of the workload
+func getPodSpec(workload *controllerUtils.Workload) (corev1.PodSpec, error) {
+	var podSpec corev1.PodSpec
+	podSpecUnstructured, found, err := unstructured.NestedMap(workload.TopController.UnstructuredContent(), "spec", "template", "spec")
+	if err != nil {
+		return podSpec, fmt.Errorf("unable to parse spec.template.spec of %s %s/%s: %v", workload.TopController.GetKind(), workload.TopController.GetNamespace(), workload.TopController.GetName(), err)
+	}
+
+	if found {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(podSpecUnstructured, &podSpec); err != nil {
+			return podSpec, fmt.Errorf("unable to convert the pod spec of %s %s/%s: %v", workload.TopController.GetKind(), workload.TopController.GetNamespace(), workload.TopController.GetName(), err)
+		}
+		return podSpec, nil
+	}
+
+	// fallback to the workload's pod spec, copied because cached workloads are shared between summaries
+	if workload.PodSpec == nil {
+		return podSpec, fmt.Errorf("no pod spec found for %s %s/%s", workload.TopController.GetKind(), workload.TopController.GetNamespace(), workload.TopController.GetName())
+	}
+	return *workload.PodSpec.DeepCopy(), nil
+}
+
+// getNamespace returns the namespace, caching it for the rest of the summary. It returns a namespace
+// without labels and annotations if the namespace can't be found.
+func (s Summarizer) getNamespace(namespace string, cache map[string]*corev1.Namespace) *corev1.Namespace {
+	if ns, ok := cache[namespace]; ok {
+		return ns
+	}
+	if s.cache.Healthy() {
+		if ns, ok := s.cache.getNamespace(namespace); ok {
+			cache[namespace] = ns
+			return ns
+		}
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+		cache[namespace] = ns
+		return ns
+	}
+
+	ns, err := s.kubeClient.Client.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+	if err != nil {
+		klog.V(2).Infof("unable to get namespace %s: %v", namespace, err)
+		ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+	}
+	cache[namespace] = ns
+	return ns
+}
+
+// getNode returns the node, looked up once per summary. Nodes that can't be looked up have no labels.
+func (s Summarizer) getNode(name string, cache map[string]*corev1.Node) *corev1.Node {
+	if node, ok := cache[name]; ok {
+		return node
+	}
+	if s.cache.Healthy() {
+		if node, ok := s.cache.getNode(name); ok {
+			cache[name] = node
+			return node
+		}
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		cache[name] = node
+		return node
+	}
+
+	node, err := s.kubeClient.Client.CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		klog.V(2).Infof("unable to get node %s: %v", name, err)
+		node = &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	cache[name] = node
+	return node
+}
+
 // Update the set of VPAs and Workloads that the Summarizer uses for creating a summary
 func (s *Summarizer) Update() error {
 	err := s.updateVPAs()
@@ -282,6 +543,9 @@ func (s *Summarizer) updateVPAs() error {
 }
 
 func (s Summarizer) listVPAs(listOptions metav1.ListOptions) ([]vpav1.VerticalPodAutoscaler, error) {
+	if s.cache.Healthy() {
+		return s.cache.listVPAs(s.namespace, s.vpaLabels)
+	}
 	vpas, err := s.vpaClient.Client.AutoscalingV1().VerticalPodAutoscalers(s.namespace).List(context.TODO(), listOptions)
 	if err != nil {
 		return nil, err
@@ -307,14 +571,13 @@ func (s *Summarizer) updateWorkloads() error {
 	}
 	klog.V(10).Infof("Found workloads in namespace '%s': %v", s.namespace, workloads)
 
-	// map goldilocks-workload.name -> &controllerUtils.Workload{} for easy vpa lookup.
+	// map vpa namespace/name -> &controllerUtils.Workload{} for easy vpa lookup.
 	s.workloadForVPANamed = map[string]*controllerUtils.Workload{}
 	for _, w := range workloads {
 		for _, v := range s.vpas {
 			w := w
 			if vpaMatchesWorkload(v, w) {
-				vpaName := v.Name
-				s.workloadForVPANamed[vpaName] = &w
+				s.workloadForVPANamed[vpaKey(v)] = &w
 			}
 		}
 	}
@@ -325,6 +588,9 @@ func (s *Summarizer) updateWorkloads() error {
 // vpaMatchesWorkload returns true if the VPA's target matches the workload
 func vpaMatchesWorkload(v vpav1.VerticalPodAutoscaler, w controllerUtils.Workload) bool {
 	// check if the VPA's target matches the workload's target
+	if v.Namespace != w.TopController.GetNamespace() {
+		return false
+	}
 	if v.Spec.TargetRef.Kind != w.TopController.GetKind() {
 		return false
 	}
@@ -337,7 +603,36 @@ func vpaMatchesWorkload(v vpav1.VerticalPodAutoscaler, w controllerUtils.Workloa
 	return true
 }
 
+// vpaKey returns the key of the VPA in workloadForVPANamed. VPA names are only unique within a namespace.
+func vpaKey(v vpav1.VerticalPodAutoscaler) string {
+	return v.Namespace + "/" + v.Name
+}
+
+// preferredVPAsFirst returns the VPAs ordered so that the VPA summarized for a workload targeted by several
+// VPAs comes first: VPAs managed by goldilocks, then VPAs with recommendations, then by name.
+func preferredVPAsFirst(vpas []vpav1.VerticalPodAutoscaler) []vpav1.VerticalPodAutoscaler {
+	managed := labels.SelectorFromSet(utils.VPALabels)
+	hasRecommendation := func(v vpav1.VerticalPodAutoscaler) bool {
+		return v.Status.Recommendation != nil && len(v.Status.Recommendation.ContainerRecommendations) > 0
+	}
+
+	sorted := append([]vpav1.VerticalPodAutoscaler{}, vpas...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if mi, mj := managed.Matches(labels.Set(sorted[i].Labels)), managed.Matches(labels.Set(sorted[j].Labels)); mi != mj {
+			return mi
+		}
+		if ri, rj := hasRecommendation(sorted[i]), hasRecommendation(sorted[j]); ri != rj {
+			return ri
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
 func (s Summarizer) listWorkloads() ([]controllerUtils.Workload, error) {
+	if s.cache.Healthy() {
+		return s.cache.listWorkloads(s.namespace), nil
+	}
 	workloads, err := s.controllerUtilsClient.Client.GetAllTopControllersSummary(s.namespace)
 	if err != nil {
 		return nil, err
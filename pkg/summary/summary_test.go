@@ -19,9 +19,14 @@ import (
 	"testing"
 
 	"github.com/fairwindsops/goldilocks/pkg/kube"
+	"github.com/fairwindsops/goldilocks/pkg/utils"
 	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
 )
 
 func Test_Summarizer(t *testing.T) {
@@ -61,7 +66,28 @@ func Test_Summarizer(t *testing.T) {
 	got, err := summarizer.GetSummary()
 	assert.NoError(t, err)
 
-	assert.EqualValues(t, testSummary, got)
+	assert.Equal(t, int32(1), got.Totals.Replicas)
+	assert.Equal(t, "100m -> 100m (0)", got.Totals.CPU())
+	assert.Equal(t, "100Mi -> 100Mi (0)", got.Namespaces["testing"].Totals.Memory())
+	assert.EqualValues(t, testSummary, withoutTotals(got))
+}
+
+// withoutTotals returns a copy of the summary without the totals, which are compared as strings
+func withoutTotals(data Summary) Summary {
+	data.Totals = Totals{}
+	namespaces := map[string]NamespaceSummary{}
+	for name, ns := range data.Namespaces {
+		ns.Totals = Totals{}
+		workloads := map[string]WorkloadSummary{}
+		for key, workload := range ns.Workloads {
+			workload.Totals = Totals{}
+			workloads[key] = workload
+		}
+		ns.Workloads = workloads
+		namespaces[name] = ns
+	}
+	data.Namespaces = namespaces
+	return data
 }
 
 func Test_Summarizer_Daemonset(t *testing.T) {
@@ -87,5 +113,300 @@ func Test_Summarizer_Daemonset(t *testing.T) {
 	got, err := summarizer.GetSummary()
 	assert.NoError(t, err)
 
-	assert.EqualValues(t, testSummaryDaemonSet, got)
+	assert.EqualValues(t, testSummaryDaemonSet, withoutTotals(got))
+}
+
+func Test_Summarizer_Headroom(t *testing.T) {
+	tests := []struct {
+		name                string
+		namespaceAnnotation string
+		workloadAnnotation  string
+		headroom            utils.Headroom
+		wantTarget          string
+	}{
+		{name: "no headroom"},
+		{name: "global headroom", headroom: utils.Headroom{v1.ResourceCPU: 50}, wantTarget: "150m"},
+		{name: "namespace annotation", namespaceAnnotation: "cpu=20%", headroom: utils.Headroom{v1.ResourceCPU: 50}, wantTarget: "120m"},
+		{name: "workload annotation", namespaceAnnotation: "cpu=20%", workloadAnnotation: "cpu=10%", wantTarget: "110m"},
+		{name: "invalid workload annotation", namespaceAnnotation: "cpu=20%", workloadAnnotation: "cpu", wantTarget: "120m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClientVPA := kube.GetMockVPAClient()
+			kubeClient := kube.GetMockClient()
+			dynamicClient := kube.GetMockDynamicClient()
+			controllerUtilsClient := kube.GetMockControllerUtilsClient(dynamicClient)
+
+			summarizer := NewSummarizer(WithHeadroom(tt.headroom))
+			summarizer.kubeClient = kubeClient
+			summarizer.vpaClient = kubeClientVPA
+			summarizer.dynamicClient = dynamicClient
+			summarizer.controllerUtilsClient = controllerUtilsClient
+
+			ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "testing-daemonset"}}
+			if tt.namespaceAnnotation != "" {
+				ns.Annotations = map[string]string{utils.RecommendationHeadroomAnnotation: tt.namespaceAnnotation}
+			}
+			_, err := kubeClient.Client.CoreV1().Namespaces().Create(context.TODO(), ns, metav1.CreateOptions{})
+			assert.NoError(t, err)
+
+			daemonSet := testDaemonSettWithRecoUnstructured.DeepCopy()
+			if tt.workloadAnnotation != "" {
+				daemonSet.SetAnnotations(map[string]string{utils.RecommendationHeadroomAnnotation: tt.workloadAnnotation})
+			}
+			_, err = dynamicClient.Client.Resource(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}).Namespace("testing-daemonset").Create(context.TODO(), daemonSet, metav1.CreateOptions{})
+			assert.NoError(t, err)
+			_, err = dynamicClient.Client.Resource(schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}).Namespace("testing-daemonset").Create(context.TODO(), testDaemonSetWithRecoPodUnstructured, metav1.CreateOptions{})
+			assert.NoError(t, err)
+			_, err = kubeClientVPA.Client.AutoscalingV1().VerticalPodAutoscalers("testing-daemonset").Create(context.TODO(), testDaemonSetVPAWithReco, metav1.CreateOptions{})
+			assert.NoError(t, err)
+
+			got, err := summarizer.GetSummary()
+			assert.NoError(t, err)
+
+			container := got.Namespaces["testing-daemonset"].Workloads["apps/v1/DaemonSet/test-ds-with-reco"].Containers["container"]
+			if tt.wantTarget == "" {
+				assert.Nil(t, container.RawTarget)
+				assert.Equal(t, "100m", container.Target.Cpu().String())
+				return
+			}
+			assert.Equal(t, "100m", container.RawTarget.Cpu().String())
+			assert.Equal(t, tt.wantTarget, container.Target.Cpu().String())
+			assert.Equal(t, "100Mi", container.Target.Memory().String())
+		})
+	}
+}
+
+func Test_Summarizer_LimitStrategy(t *testing.T) {
+	tests := []struct {
+		name                string
+		namespaceAnnotation string
+		limitStrategy       utils.LimitStrategy
+		wantRequests        v1.ResourceList
+		wantLimits          v1.ResourceList
+	}{
+		{name: "no strategy"},
+		{
+			name:          "burstable",
+			limitStrategy: utils.LimitStrategy{Burstable: true},
+			wantRequests:  lowerBound,
+			wantLimits:    upperBound,
+		},
+		{
+			name:                "namespace annotation",
+			namespaceAnnotation: "no-cpu-limit,ratio:1.5",
+			limitStrategy:       utils.LimitStrategy{Burstable: true},
+			wantRequests:        targetResources,
+			wantLimits:          v1.ResourceList{v1.ResourceMemory: resource.MustParse("150Mi")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClientVPA := kube.GetMockVPAClient()
+			kubeClient := kube.GetMockClient()
+			dynamicClient := kube.GetMockDynamicClient()
+			controllerUtilsClient := kube.GetMockControllerUtilsClient(dynamicClient)
+
+			summarizer := NewSummarizer(WithLimitStrategy(tt.limitStrategy))
+			summarizer.kubeClient = kubeClient
+			summarizer.vpaClient = kubeClientVPA
+			summarizer.dynamicClient = dynamicClient
+			summarizer.controllerUtilsClient = controllerUtilsClient
+
+			ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "testing-daemonset"}}
+			if tt.namespaceAnnotation != "" {
+				ns.Annotations = map[string]string{utils.LimitStrategyAnnotation: tt.namespaceAnnotation}
+			}
+			_, err := kubeClient.Client.CoreV1().Namespaces().Create(context.TODO(), ns, metav1.CreateOptions{})
+			assert.NoError(t, err)
+			_, err = dynamicClient.Client.Resource(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}).Namespace("testing-daemonset").Create(context.TODO(), testDaemonSettWithRecoUnstructured, metav1.CreateOptions{})
+			assert.NoError(t, err)
+			_, err = dynamicClient.Client.Resource(schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}).Namespace("testing-daemonset").Create(context.TODO(), testDaemonSetWithRecoPodUnstructured, metav1.CreateOptions{})
+			assert.NoError(t, err)
+			_, err = kubeClientVPA.Client.AutoscalingV1().VerticalPodAutoscalers("testing-daemonset").Create(context.TODO(), testDaemonSetVPAWithReco, metav1.CreateOptions{})
+			assert.NoError(t, err)
+
+			got, err := summarizer.GetSummary()
+			assert.NoError(t, err)
+
+			workload := got.Namespaces["testing-daemonset"].Workloads["apps/v1/DaemonSet/test-ds-with-reco"]
+			container := workload.Containers["container"]
+			if tt.wantLimits == nil {
+				assert.True(t, workload.LimitStrategy.IsZero())
+				assert.Nil(t, container.RecommendedRequests)
+				assert.Nil(t, container.RecommendedLimits)
+				return
+			}
+			assert.Equal(t, tt.wantRequests.Cpu().String(), container.RecommendedRequests.Cpu().String())
+			assert.Equal(t, tt.wantRequests.Memory().String(), container.RecommendedRequests.Memory().String())
+			assert.Equal(t, len(tt.wantLimits), len(container.RecommendedLimits))
+			for name, quantity := range tt.wantLimits {
+				assert.Equal(t, quantity.String(), container.RecommendedLimits.Name(name, resource.BinarySI).String())
+			}
+		})
+	}
+}
+
+func Test_Summarizer_InitContainers(t *testing.T) {
+	kubeClientVPA := kube.GetMockVPAClient()
+	kubeClient := kube.GetMockClient()
+	dynamicClient := kube.GetMockDynamicClient()
+	controllerUtilsClient := kube.GetMockControllerUtilsClient(dynamicClient)
+
+	summarizer := NewSummarizer()
+	summarizer.kubeClient = kubeClient
+	summarizer.vpaClient = kubeClientVPA
+	summarizer.dynamicClient = dynamicClient
+	summarizer.controllerUtilsClient = controllerUtilsClient
+
+	daemonSet := testDaemonSettWithRecoUnstructured.DeepCopy()
+	err := unstructured.SetNestedSlice(daemonSet.Object, []any{
+		map[string]any{
+			"name":          "proxy",
+			"restartPolicy": "Always",
+			"resources": map[string]any{
+				"requests": map[string]any{"cpu": "10m", "memory": "16Mi"},
+			},
+		},
+		map[string]any{
+			"name": "migrate",
+		},
+	}, "spec", "template", "spec", "initContainers")
+	assert.NoError(t, err)
+
+	vpa := testDaemonSetVPAWithReco.DeepCopy()
+	for _, name := range []string{"proxy", "migrate"} {
+		vpa.Status.Recommendation.ContainerRecommendations = append(vpa.Status.Recommendation.ContainerRecommendations, vpav1.RecommendedContainerResources{
+			ContainerName: name,
+			Target:        targetResources.DeepCopy(),
+			UpperBound:    upperBound.DeepCopy(),
+			LowerBound:    lowerBound.DeepCopy(),
+		})
+	}
+
+	_, err = dynamicClient.Client.Resource(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}).Namespace("testing-daemonset").Create(context.TODO(), daemonSet, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	_, err = dynamicClient.Client.Resource(schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}).Namespace("testing-daemonset").Create(context.TODO(), testDaemonSetWithRecoPodUnstructured, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	_, err = kubeClientVPA.Client.AutoscalingV1().VerticalPodAutoscalers("testing-daemonset").Create(context.TODO(), vpa, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	got, err := summarizer.GetSummary()
+	assert.NoError(t, err)
+
+	containers := got.Namespaces["testing-daemonset"].Workloads["apps/v1/DaemonSet/test-ds-with-reco"].Containers
+	assert.Len(t, containers, 3)
+	assert.Equal(t, ContainerTypeContainer, containers["container"].ContainerType)
+	assert.Equal(t, ContainerTypeSidecar, containers["proxy"].ContainerType)
+	proxyRequests := containers["proxy"].Requests
+	assert.Equal(t, "10m", proxyRequests.Cpu().String())
+	assert.Equal(t, ContainerTypeInit, containers["migrate"].ContainerType)
+}
+
+func Test_Summarizer_Warnings(t *testing.T) {
+	kubeClientVPA := kube.GetMockVPAClient()
+	kubeClient := kube.GetMockClient()
+	dynamicClient := kube.GetMockDynamicClient()
+	controllerUtilsClient := kube.GetMockControllerUtilsClient(dynamicClient)
+
+	summarizer := NewSummarizer()
+	summarizer.kubeClient = kubeClient
+	summarizer.vpaClient = kubeClientVPA
+	summarizer.dynamicClient = dynamicClient
+	summarizer.controllerUtilsClient = controllerUtilsClient
+
+	vpa := testDaemonSetVPAWithReco.DeepCopy()
+	vpa.Status.Recommendation.ContainerRecommendations = append(vpa.Status.Recommendation.ContainerRecommendations, vpav1.RecommendedContainerResources{
+		ContainerName: "removed",
+		Target:        targetResources.DeepCopy(),
+	})
+	orphan := testDaemonSetVPAWithReco.DeepCopy()
+	orphan.Name = "goldilocks-deleted"
+	orphan.Spec.TargetRef.Name = "deleted"
+
+	_, err := dynamicClient.Client.Resource(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}).Namespace("testing-daemonset").Create(context.TODO(), testDaemonSettWithRecoUnstructured, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	_, err = dynamicClient.Client.Resource(schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}).Namespace("testing-daemonset").Create(context.TODO(), testDaemonSetWithRecoPodUnstructured, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	_, err = kubeClientVPA.Client.AutoscalingV1().VerticalPodAutoscalers("testing-daemonset").Create(context.TODO(), vpa, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	_, err = kubeClientVPA.Client.AutoscalingV1().VerticalPodAutoscalers("testing-daemonset").Create(context.TODO(), orphan, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	got, err := summarizer.GetSummary()
+	assert.NoError(t, err)
+
+	workloads := got.Namespaces["testing-daemonset"].Workloads
+	assert.Equal(t, WorkloadStatusContainerMissing, workloads["apps/v1/DaemonSet/test-ds-with-reco"].Status)
+	assert.Len(t, workloads["apps/v1/DaemonSet/test-ds-with-reco"].Containers, 1)
+	assert.Equal(t, WorkloadStatusWorkloadNotFound, workloads["apps/v1/DaemonSet/deleted"].Status)
+
+	warnings := map[WorkloadStatus]Warning{}
+	for _, warning := range got.Warnings {
+		warnings[warning.Type] = warning
+	}
+	assert.Len(t, warnings, 2)
+	assert.Equal(t, "removed", warnings[WorkloadStatusContainerMissing].Container)
+	assert.Equal(t, "goldilocks-deleted", warnings[WorkloadStatusWorkloadNotFound].VPA)
+}
+
+func Test_Summarizer_WorkloadKeys(t *testing.T) {
+	kubeClientVPA := kube.GetMockVPAClient()
+	kubeClient := kube.GetMockClient()
+	dynamicClient := kube.GetMockDynamicClient()
+	controllerUtilsClient := kube.GetMockControllerUtilsClient(dynamicClient)
+
+	// all VPAs, like the dashboard with --show-all
+	summarizer := NewSummarizer(ForVPAsWithLabels(map[string]string{}))
+	summarizer.kubeClient = kubeClient
+	summarizer.vpaClient = kubeClientVPA
+	summarizer.dynamicClient = dynamicClient
+	summarizer.controllerUtilsClient = controllerUtilsClient
+
+	// a second VPA for the same DaemonSet, not managed by goldilocks
+	userVPA := testDaemonSetVPAWithReco.DeepCopy()
+	userVPA.Name = "a-user-vpa"
+	userVPA.Labels = nil
+	userVPA.Status.Recommendation.ContainerRecommendations[0].ContainerName = "other"
+
+	// a VPA for a Deployment with the same name as the DaemonSet
+	deploymentVPA := testDaemonSetVPAWithReco.DeepCopy()
+	deploymentVPA.Name = "goldilocks-test-ds-with-reco-deployment"
+	deploymentVPA.Spec.TargetRef.Kind = "Deployment"
+
+	_, err := dynamicClient.Client.Resource(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}).Namespace("testing-daemonset").Create(context.TODO(), testDaemonSettWithRecoUnstructured, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	_, err = dynamicClient.Client.Resource(schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}).Namespace("testing-daemonset").Create(context.TODO(), testDaemonSetWithRecoPodUnstructured, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	for _, vpa := range []*vpav1.VerticalPodAutoscaler{userVPA, testDaemonSetVPAWithReco, deploymentVPA} {
+		_, err = kubeClientVPA.Client.AutoscalingV1().VerticalPodAutoscalers("testing-daemonset").Create(context.TODO(), vpa, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	got, err := summarizer.GetSummary()
+	assert.NoError(t, err)
+
+	workloads := got.Namespaces["testing-daemonset"].Workloads
+	assert.Len(t, workloads, 2)
+	daemonSet := workloads["apps/v1/DaemonSet/test-ds-with-reco"]
+	assert.Equal(t, WorkloadStatusOK, daemonSet.Status, "the goldilocks VPA is preferred")
+	assert.Contains(t, daemonSet.Containers, "container")
+	assert.Equal(t, "apps/v1/DaemonSet/test-ds-with-reco", daemonSet.Key())
+	assert.Equal(t, WorkloadStatusWorkloadNotFound, workloads["apps/v1/Deployment/test-ds-with-reco"].Status)
+}
+
+func Test_preferredVPAsFirst(t *testing.T) {
+	managed := vpav1.VerticalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "z", Labels: utils.VPALabels}}
+	withReco := vpav1.VerticalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "y"},
+		Status: vpav1.VerticalPodAutoscalerStatus{Recommendation: &vpav1.RecommendedPodResources{
+			ContainerRecommendations: []vpav1.RecommendedContainerResources{{ContainerName: "app"}},
+		}},
+	}
+	other := vpav1.VerticalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "a"}}
+
+	vpas := []vpav1.VerticalPodAutoscaler{other, withReco, managed}
+	got := preferredVPAsFirst(vpas)
+	assert.Equal(t, []string{"z", "y", "a"}, []string{got[0].Name, got[1].Name, got[2].Name})
+	assert.Equal(t, "a", vpas[0].Name, "the VPAs are not reordered in place")
 }
@@ -0,0 +1,180 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"fmt"
+
+	controllerUtils "github.com/fairwindsops/controller-utils/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// totalResources are the resources that are added up in the totals
+var totalResources = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory}
+
+// Totals are the current and recommended cpu and memory requests of all the replicas of one
+// or more workloads
+type Totals struct {
+	// Replicas is the number of pods
+	Replicas int32 `json:"replicas"`
+	// Requests are the current requests of all the pods
+	Requests corev1.ResourceList `json:"requests"`
+	// Recommended are the recommended requests of all the pods
+	Recommended corev1.ResourceList `json:"recommended"`
+	// Cost is the cost of the current resources of all the pods, when the summary has a cost model
+	Cost *Cost `json:"cost,omitempty"`
+}
+
+// add adds the requests and recommendations of a container, multiplied by the replicas
+func (t *Totals) add(replicas int32, requests, recommended corev1.ResourceList) {
+	t.init()
+	for _, name := range totalResources {
+		t.Requests[name] = addQuantity(t.Requests[name], multiplyQuantity(requests[name], replicas))
+		t.Recommended[name] = addQuantity(t.Recommended[name], multiplyQuantity(recommended[name], replicas))
+	}
+}
+
+// merge adds the other totals
+func (t *Totals) merge(other Totals) {
+	t.init()
+	t.Replicas += other.Replicas
+	if other.Cost != nil {
+		cost := *other.Cost
+		if t.Cost != nil {
+			cost = t.Cost.add(cost)
+		}
+		t.Cost = &cost
+	}
+	for _, name := range totalResources {
+		t.Requests[name] = addQuantity(t.Requests[name], other.Requests[name])
+		t.Recommended[name] = addQuantity(t.Recommended[name], other.Recommended[name])
+	}
+}
+
+func (t *Totals) init() {
+	if t.Requests == nil {
+		t.Requests = corev1.ResourceList{}
+	}
+	if t.Recommended == nil {
+		t.Recommended = corev1.ResourceList{}
+	}
+	for _, name := range totalResources {
+		if _, ok := t.Requests[name]; !ok {
+			t.Requests[name] = resource.Quantity{Format: formatForResource(name)}
+		}
+		if _, ok := t.Recommended[name]; !ok {
+			t.Recommended[name] = resource.Quantity{Format: formatForResource(name)}
+		}
+	}
+}
+
+// CPU describes the total cpu requests, e.g. "3 -> 1500m (-1500m)"
+func (t Totals) CPU() string {
+	return t.describe(corev1.ResourceCPU)
+}
+
+// Memory describes the total memory requests, e.g. "4Gi -> 3Gi (-1Gi)"
+func (t Totals) Memory() string {
+	return t.describe(corev1.ResourceMemory)
+}
+
+func (t Totals) describe(name corev1.ResourceName) string {
+	requests, recommended := t.Requests[name], t.Recommended[name]
+	return fmt.Sprintf("%s -> %s (%s)", requests.String(), recommended.String(), formatDelta(delta(requests, recommended)))
+}
+
+// setTotals sets the totals of every workload and namespace, and of the whole summary
+func setTotals(data *Summary) {
+	data.Totals = Totals{}
+	data.Totals.init()
+	for nsName, ns := range data.Namespaces {
+		ns.Totals = Totals{}
+		ns.Totals.init()
+		for key, workload := range ns.Workloads {
+			workload.Totals = workloadTotals(workload)
+			ns.Totals.merge(workload.Totals)
+			ns.Workloads[key] = workload
+		}
+		data.Totals.merge(ns.Totals)
+		data.Namespaces[nsName] = ns
+	}
+}
+
+// workloadTotals adds up the requests and recommendations of the containers of the workload
+func workloadTotals(workload WorkloadSummary) Totals {
+	totals := Totals{Replicas: workload.Replicas, Cost: workloadCost(workload)}
+	totals.init()
+	for _, c := range workload.Containers {
+		totals.add(workload.Replicas, c.Requests, c.recommendedRequests())
+	}
+	return totals
+}
+
+// summaryTotals adds up the totals of all the workloads of the summary, without modifying it
+func summaryTotals(data Summary) Totals {
+	var totals Totals
+	totals.init()
+	for _, ns := range data.Namespaces {
+		for _, workload := range ns.Workloads {
+			totals.merge(workloadTotals(workload))
+		}
+	}
+	return totals
+}
+
+// getReplicas returns the number of pods of the workload: the nodes a DaemonSet should be
+// scheduled on, spec.replicas for the workloads that have it, and the number of pods otherwise
+func getReplicas(workload *controllerUtils.Workload) int32 {
+	content := workload.TopController.UnstructuredContent()
+	if workload.TopController.GetKind() == "DaemonSet" {
+		if scheduled, found, err := unstructured.NestedInt64(content, "status", "desiredNumberScheduled"); err == nil && found {
+			return int32(scheduled)
+		}
+		return int32(workload.PodCount)
+	}
+
+	if replicas, found, err := unstructured.NestedInt64(content, "spec", "replicas"); err == nil && found {
+		return int32(replicas)
+	}
+	switch workload.TopController.GetKind() {
+	case "Deployment", "StatefulSet", "ReplicaSet", "ReplicationController":
+		// spec.replicas defaults to 1
+		return 1
+	}
+	return int32(workload.PodCount)
+}
+
+func multiplyQuantity(q resource.Quantity, n int32) resource.Quantity {
+	// keep whole numbers, such as memory in bytes, out of the milli scale
+	if q.MilliValue()%1000 != 0 {
+		return *resource.NewMilliQuantity(q.MilliValue()*int64(n), q.Format)
+	}
+	return *resource.NewQuantity(q.Value()*int64(n), q.Format)
+}
+
+func addQuantity(a, b resource.Quantity) resource.Quantity {
+	sum := a.DeepCopy()
+	sum.Add(b)
+	return sum
+}
+
+func formatForResource(name corev1.ResourceName) resource.Format {
+	if name == corev1.ResourceCPU {
+		return resource.DecimalSI
+	}
+	return resource.BinarySI
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"testing"
+
+	controllerUtils "github.com/fairwindsops/controller-utils/pkg/controller"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSetTotals(t *testing.T) {
+	data := testOutputSummary(t)
+	setTotals(&data)
+
+	web := data.Namespaces["testing"].Workloads["web"]
+	assert.Equal(t, int32(3), web.Totals.Replicas)
+	assert.Equal(t, "1500m -> 300m (-1200m)", web.Totals.CPU())
+	assert.Equal(t, "384Mi -> 384Mi (0)", web.Totals.Memory())
+
+	// the recommended requests of the limit strategy are used when there are any
+	db := data.Namespaces["testing"].Workloads["db"]
+	assert.Equal(t, "2Gi", db.Totals.Recommended.Memory().String())
+
+	assert.Equal(t, int32(5), data.Namespaces["testing"].Totals.Replicas)
+	assert.Equal(t, "1900m -> 800m (-1100m)", data.Namespaces["testing"].Totals.CPU())
+	assert.Equal(t, data.Namespaces["testing"].Totals, data.Totals)
+	assert.Equal(t, data.Totals, summaryTotals(data))
+}
+
+func TestSetTotalsCost(t *testing.T) {
+	data := testOutputSummary(t)
+	web := data.Namespaces["testing"].Workloads["web"]
+	for name, c := range web.Containers {
+		c.Costs = &ContainerCosts{Current: newCost(1.5, 0.5)}
+		web.Containers[name] = c
+	}
+	setTotals(&data)
+
+	assert.Equal(t, &Cost{CPU: 1.5, Memory: 0.5, Total: 2}, data.Namespaces["testing"].Workloads["web"].Totals.Cost)
+	assert.Nil(t, data.Namespaces["testing"].Workloads["db"].Totals.Cost)
+	assert.Equal(t, &Cost{CPU: 1.5, Memory: 0.5, Total: 2}, data.Totals.Cost)
+}
+
+func TestGetReplicas(t *testing.T) {
+	tests := []struct {
+		name   string
+		object map[string]any
+		pods   int
+		want   int32
+	}{
+		{name: "deployment", object: map[string]any{"kind": "Deployment", "spec": map[string]any{"replicas": int64(50)}}, want: 50},
+		{name: "deployment scaled to zero", object: map[string]any{"kind": "Deployment", "spec": map[string]any{"replicas": int64(0)}}, pods: 1, want: 0},
+		{name: "deployment without replicas", object: map[string]any{"kind": "Deployment", "spec": map[string]any{}}, want: 1},
+		{name: "daemonset", object: map[string]any{"kind": "DaemonSet", "status": map[string]any{"desiredNumberScheduled": int64(12)}}, pods: 3, want: 12},
+		{name: "daemonset without status", object: map[string]any{"kind": "DaemonSet"}, pods: 3, want: 3},
+		{name: "cronjob", object: map[string]any{"kind": "CronJob"}, pods: 2, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workload := &controllerUtils.Workload{
+				TopController: unstructured.Unstructured{Object: tt.object},
+				PodCount:      tt.pods,
+			}
+			assert.Equal(t, tt.want, getReplicas(workload))
+		})
+	}
+}
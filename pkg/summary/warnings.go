@@ -0,0 +1,57 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+// WorkloadStatus is whether the recommendations of a workload could be summarized
+type WorkloadStatus string
+
+const (
+	// WorkloadStatusOK means the recommendations of every container were summarized
+	WorkloadStatusOK WorkloadStatus = "OK"
+	// WorkloadStatusNoRecommendationYet means the VPA has not made any recommendations yet
+	WorkloadStatusNoRecommendationYet WorkloadStatus = "NoRecommendationYet"
+	// WorkloadStatusWorkloadNotFound means the workload targeted by the VPA was not found
+	WorkloadStatusWorkloadNotFound WorkloadStatus = "WorkloadNotFound"
+	// WorkloadStatusPodSpecUnparsable means the pod spec of the workload could not be read
+	WorkloadStatusPodSpecUnparsable WorkloadStatus = "PodSpecUnparsable"
+	// WorkloadStatusContainerMissing means the VPA has a recommendation for a container that is not in the pod spec
+	WorkloadStatusContainerMissing WorkloadStatus = "ContainerMissing"
+)
+
+// Description is the explanation of the status shown on the dashboard
+func (s WorkloadStatus) Description() string {
+	switch s {
+	case WorkloadStatusNoRecommendationYet:
+		return "The VPA has not made any recommendations yet. Recommendations usually show up a few minutes after the VPA is created."
+	case WorkloadStatusWorkloadNotFound:
+		return "The workload targeted by the VPA was not found."
+	case WorkloadStatusPodSpecUnparsable:
+		return "The pod spec of the workload could not be read."
+	case WorkloadStatusContainerMissing:
+		return "The VPA has recommendations for containers that are not in the pod spec of the workload."
+	}
+	return ""
+}
+
+// Warning is a problem with a VPA that could not be summarized completely
+type Warning struct {
+	Type      WorkloadStatus `json:"type"`
+	Namespace string         `json:"namespace"`
+	VPA       string         `json:"vpa"`
+	Kind      string         `json:"kind"`
+	Workload  string         `json:"workload"`
+	Container string         `json:"container,omitempty"`
+	Message   string         `json:"message"`
+}
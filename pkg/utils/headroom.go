@@ -0,0 +1,87 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Headroom is a safety margin, as a percentage per resource, added on top of a recommendation
+type Headroom map[v1.ResourceName]float64
+
+// ParseHeadroom parses a comma delimited list of per-resource percentages, for example "cpu=20%,memory=10%"
+func ParseHeadroom(value string) (Headroom, error) {
+	headroom := Headroom{}
+	if strings.TrimSpace(value) == "" {
+		return headroom, nil
+	}
+
+	for _, item := range strings.Split(value, ",") {
+		name, amount, found := strings.Cut(strings.TrimSpace(item), "=")
+		percent, isPercent := strings.CutSuffix(strings.TrimSpace(amount), "%")
+		if !found || name == "" || !isPercent {
+			return headroom, fmt.Errorf("invalid headroom %q, expected <resource>=<percent>%%", item)
+		}
+		parsed, err := strconv.ParseFloat(percent, 64)
+		if err != nil || parsed < 0 {
+			return headroom, fmt.Errorf("invalid headroom percentage %q for %s", amount, name)
+		}
+		headroom[v1.ResourceName(strings.TrimSpace(name))] = parsed
+	}
+	return headroom, nil
+}
+
+// Apply returns a copy of the resource list with the headroom added, rounded up to the next
+// millicore for cpu and the next byte for everything else
+func (h Headroom) Apply(rl v1.ResourceList) v1.ResourceList {
+	if rl == nil || len(h) == 0 {
+		return rl
+	}
+
+	adjusted := rl.DeepCopy()
+	for name, percent := range h {
+		quantity, exists := adjusted[name]
+		if !exists || percent == 0 {
+			continue
+		}
+		if name == v1.ResourceCPU {
+			value := quantity.MilliValue()
+			quantity = *resource.NewMilliQuantity(value+int64(math.Ceil(float64(value)*percent/100)), quantity.Format)
+		} else {
+			value := quantity.Value()
+			quantity = *resource.NewQuantity(value+int64(math.Ceil(float64(value)*percent/100)), quantity.Format)
+		}
+		_ = quantity.String()
+		adjusted[name] = quantity
+	}
+	return adjusted
+}
+
+// String formats the headroom the same way it is parsed, for example "cpu=20%,memory=10%"
+func (h Headroom) String() string {
+	items := make([]string, 0, len(h))
+	for name, percent := range h {
+		items = append(items, fmt.Sprintf("%s=%s%%", name, strconv.FormatFloat(percent, 'f', -1, 64)))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
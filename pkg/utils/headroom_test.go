@@ -0,0 +1,52 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestHeadroom(t *testing.T) {
+	headroom, err := ParseHeadroom("cpu=20%, memory=12.5%")
+	assert.NoError(t, err)
+	assert.Equal(t, Headroom{v1.ResourceCPU: 20, v1.ResourceMemory: 12.5}, headroom)
+	assert.Equal(t, "cpu=20%,memory=12.5%", headroom.String())
+
+	input := v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("100m"),
+		v1.ResourceMemory: resource.MustParse("128Mi"),
+	}
+	got := headroom.Apply(input)
+	assert.Equal(t, "120m", got.Cpu().String())
+	assert.Equal(t, "144Mi", got.Memory().String())
+	assert.Equal(t, "100m", input.Cpu().String(), "input is not modified")
+
+	cpuOnly := Headroom{v1.ResourceCPU: 10}.Apply(input)
+	assert.Equal(t, "110m", cpuOnly.Cpu().String())
+	assert.Equal(t, "128Mi", cpuOnly.Memory().String())
+
+	empty, err := ParseHeadroom("")
+	assert.NoError(t, err)
+	assert.Empty(t, empty)
+
+	for _, invalid := range []string{"cpu", "cpu=20", "cpu=abc%", "memory=-1%"} {
+		_, err := ParseHeadroom(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// LimitStrategyGuaranteed sets requests and limits to the target
+	LimitStrategyGuaranteed = "guaranteed"
+	// LimitStrategyBurstable sets requests to the lower bound and limits to the upper bound
+	LimitStrategyBurstable = "burstable"
+	// LimitStrategyNoCPULimit sets requests to the target and leaves out the cpu limit
+	LimitStrategyNoCPULimit = "no-cpu-limit"
+	// LimitStrategyRatioPrefix sets requests to the target and limits to a multiple of it, e.g. ratio:1.5
+	LimitStrategyRatioPrefix = "ratio:"
+)
+
+// LimitStrategies are the names of the supported limit strategies
+var LimitStrategies = []string{LimitStrategyGuaranteed, LimitStrategyBurstable, LimitStrategyNoCPULimit, LimitStrategyRatioPrefix + "<n>"}
+
+// LimitStrategy decides which recommended requests and limits are derived from the VPA recommendation.
+// The zero value is unset, and behaves like the guaranteed strategy.
+type LimitStrategy struct {
+	// Burstable uses the lower and upper bounds instead of the target
+	Burstable bool
+	// Ratio is the limit as a multiple of the request
+	Ratio float64
+	// NoCPULimit leaves the cpu limit out
+	NoCPULimit bool
+}
+
+// ParseLimitStrategy parses a comma delimited limit strategy. no-cpu-limit and ratio:<n> can be
+// combined, for example "no-cpu-limit,ratio:1.5" for no cpu limit and a memory limit of 1.5 times the request.
+func ParseLimitStrategy(value string) (LimitStrategy, error) {
+	var strategy LimitStrategy
+	if strings.TrimSpace(value) == "" {
+		return strategy, nil
+	}
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == LimitStrategyGuaranteed:
+			strategy.Ratio = 1
+		case item == LimitStrategyBurstable:
+			strategy.Burstable = true
+		case item == LimitStrategyNoCPULimit:
+			strategy.NoCPULimit = true
+		case strings.HasPrefix(item, LimitStrategyRatioPrefix):
+			ratio, err := strconv.ParseFloat(strings.TrimPrefix(item, LimitStrategyRatioPrefix), 64)
+			if err != nil || ratio < 1 {
+				return LimitStrategy{}, fmt.Errorf("invalid limit ratio %q, must be a number of at least 1", item)
+			}
+			strategy.Ratio = ratio
+		default:
+			return LimitStrategy{}, fmt.Errorf("unknown limit strategy %q, must be one of %v", item, LimitStrategies)
+		}
+	}
+	if strategy.Burstable && (strategy.NoCPULimit || strategy.Ratio != 0) {
+		return LimitStrategy{}, fmt.Errorf("limit strategy %s can't be combined with other strategies", LimitStrategyBurstable)
+	}
+	if strategy.Ratio == 0 && !strategy.Burstable {
+		strategy.Ratio = 1
+	}
+	return strategy, nil
+}
+
+// IsZero returns true if no strategy has been chosen
+func (s LimitStrategy) IsZero() bool {
+	return s == LimitStrategy{}
+}
+
+// String formats the strategy the same way it is parsed
+func (s LimitStrategy) String() string {
+	if s.IsZero() {
+		return ""
+	}
+	if s.Burstable {
+		return LimitStrategyBurstable
+	}
+	var items []string
+	if s.NoCPULimit {
+		items = append(items, LimitStrategyNoCPULimit)
+	}
+	if s.Ratio != 0 && s.Ratio != 1 {
+		items = append(items, LimitStrategyRatioPrefix+strconv.FormatFloat(s.Ratio, 'f', -1, 64))
+	}
+	if len(items) == 0 {
+		return LimitStrategyGuaranteed
+	}
+	return strings.Join(items, ",")
+}
+
+// MarshalText implements encoding.TextMarshaler so the strategy is written to JSON as a string
+func (s LimitStrategy) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (s *LimitStrategy) UnmarshalText(text []byte) error {
+	parsed, err := ParseLimitStrategy(string(text))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+// Recommend returns the recommended requests and limits for a container from its VPA recommendation
+func (s LimitStrategy) Recommend(target, lowerBound, upperBound v1.ResourceList) (requests v1.ResourceList, limits v1.ResourceList) {
+	if s.Burstable {
+		return lowerBound.DeepCopy(), upperBound.DeepCopy()
+	}
+
+	requests = target.DeepCopy()
+	if requests == nil {
+		return nil, nil
+	}
+	limits = v1.ResourceList{}
+	for name, quantity := range requests {
+		if name == v1.ResourceCPU && s.NoCPULimit {
+			continue
+		}
+		limits[name] = scaleQuantity(name, quantity, s.Ratio)
+	}
+	return requests, limits
+}
+
+// scaleQuantity multiplies the quantity by the ratio, rounding up to the next millicore for cpu
+// and the next byte for everything else
+func scaleQuantity(name v1.ResourceName, quantity resource.Quantity, ratio float64) resource.Quantity {
+	if ratio == 0 || ratio == 1 {
+		return quantity.DeepCopy()
+	}
+	var scaled resource.Quantity
+	if name == v1.ResourceCPU {
+		scaled = *resource.NewMilliQuantity(int64(math.Ceil(float64(quantity.MilliValue())*ratio)), quantity.Format)
+	} else {
+		scaled = *resource.NewQuantity(int64(math.Ceil(float64(quantity.Value())*ratio)), quantity.Format)
+	}
+	_ = scaled.String()
+	return scaled
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestParseLimitStrategy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    LimitStrategy
+		wantErr bool
+	}{
+		{value: "", want: LimitStrategy{}},
+		{value: "guaranteed", want: LimitStrategy{Ratio: 1}},
+		{value: "burstable", want: LimitStrategy{Burstable: true}},
+		{value: "no-cpu-limit", want: LimitStrategy{Ratio: 1, NoCPULimit: true}},
+		{value: "ratio:1.5", want: LimitStrategy{Ratio: 1.5}},
+		{value: "no-cpu-limit, ratio:1.5", want: LimitStrategy{Ratio: 1.5, NoCPULimit: true}},
+		{value: "ratio:0.5", wantErr: true},
+		{value: "ratio:abc", wantErr: true},
+		{value: "burstable,no-cpu-limit", wantErr: true},
+		{value: "besteffort", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseLimitStrategy(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLimitStrategyString(t *testing.T) {
+	for _, value := range []string{"", "guaranteed", "burstable", "no-cpu-limit", "ratio:1.5", "no-cpu-limit,ratio:2"} {
+		strategy, err := ParseLimitStrategy(value)
+		assert.NoError(t, err)
+		assert.Equal(t, value, strategy.String())
+
+		encoded, err := json.Marshal(strategy)
+		assert.NoError(t, err)
+		var decoded LimitStrategy
+		assert.NoError(t, json.Unmarshal(encoded, &decoded))
+		assert.Equal(t, strategy, decoded)
+	}
+}
+
+func TestLimitStrategyRecommend(t *testing.T) {
+	target := v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("100m"),
+		v1.ResourceMemory: resource.MustParse("128Mi"),
+	}
+	lowerBound := v1.ResourceList{v1.ResourceCPU: resource.MustParse("10m")}
+	upperBound := v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")}
+
+	requests, limits := LimitStrategy{Ratio: 1}.Recommend(target, lowerBound, upperBound)
+	assert.Equal(t, "100m", requests.Cpu().String())
+	assert.Equal(t, "100m", limits.Cpu().String())
+	assert.Equal(t, "128Mi", limits.Memory().String())
+
+	requests, limits = LimitStrategy{Burstable: true}.Recommend(target, lowerBound, upperBound)
+	assert.Equal(t, "10m", requests.Cpu().String())
+	assert.Equal(t, "500m", limits.Cpu().String())
+
+	requests, limits = LimitStrategy{Ratio: 1.5, NoCPULimit: true}.Recommend(target, lowerBound, upperBound)
+	assert.Equal(t, "100m", requests.Cpu().String())
+	assert.Equal(t, "128Mi", requests.Memory().String())
+	_, hasCPULimit := limits[v1.ResourceCPU]
+	assert.False(t, hasCPULimit)
+	assert.Equal(t, "192Mi", limits.Memory().String())
+
+	_, limits = LimitStrategy{Ratio: 2}.Recommend(target, lowerBound, upperBound)
+	assert.Equal(t, "200m", limits.Cpu().String())
+	assert.Equal(t, "100m", target.Cpu().String(), "target is not modified")
+
+	requests, limits = LimitStrategy{Ratio: 1}.Recommend(nil, nil, nil)
+	assert.Nil(t, requests)
+	assert.Nil(t, limits)
+}
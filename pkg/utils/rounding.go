@@ -0,0 +1,123 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// RoundingMode is how recommendations are rounded to a step
+type RoundingMode string
+
+const (
+	// RoundUp rounds up to the next step
+	RoundUp RoundingMode = "up"
+	// RoundNearest rounds to the nearest step
+	RoundNearest RoundingMode = "nearest"
+)
+
+// RoundingPolicy rounds recommendations to values a human would put in a manifest
+type RoundingPolicy struct {
+	// CPUStep and MemoryStep are the steps values are rounded to. A zero step disables rounding.
+	CPUStep    resource.Quantity
+	MemoryStep resource.Quantity
+	Mode       RoundingMode
+	// MinCPU and MinMemory are floors applied after rounding
+	MinCPU    resource.Quantity
+	MinMemory resource.Quantity
+}
+
+// NewRoundingPolicy parses the steps and floors of a RoundingPolicy. Empty strings are left unset.
+func NewRoundingPolicy(cpuStep, memoryStep, mode, minCPU, minMemory string) (RoundingPolicy, error) {
+	policy := RoundingPolicy{
+		Mode: RoundingMode(mode),
+	}
+	switch policy.Mode {
+	case "":
+		policy.Mode = RoundUp
+	case RoundUp, RoundNearest:
+	default:
+		return policy, fmt.Errorf("invalid rounding mode %q, must be one of %s or %s", mode, RoundUp, RoundNearest)
+	}
+
+	for _, q := range []struct {
+		name  string
+		value string
+		into  *resource.Quantity
+	}{
+		{"cpu step", cpuStep, &policy.CPUStep},
+		{"memory step", memoryStep, &policy.MemoryStep},
+		{"minimum cpu", minCPU, &policy.MinCPU},
+		{"minimum memory", minMemory, &policy.MinMemory},
+	} {
+		if q.value == "" {
+			continue
+		}
+		parsed, err := resource.ParseQuantity(q.value)
+		if err != nil || parsed.Sign() < 0 {
+			return policy, fmt.Errorf("invalid %s %q", q.name, q.value)
+		}
+		*q.into = parsed
+	}
+	return policy, nil
+}
+
+// IsZero returns true if the policy does not change any values
+func (p RoundingPolicy) IsZero() bool {
+	return p.CPUStep.IsZero() && p.MemoryStep.IsZero() && p.MinCPU.IsZero() && p.MinMemory.IsZero()
+}
+
+// Apply returns a copy of the resource list with cpu and memory rounded and floored by the policy
+func (p RoundingPolicy) Apply(rl v1.ResourceList) v1.ResourceList {
+	if rl == nil || p.IsZero() {
+		return rl
+	}
+
+	rounded := rl.DeepCopy()
+	if cpu, exists := rounded[v1.ResourceCPU]; exists {
+		value := p.round(cpu.MilliValue(), p.CPUStep.MilliValue(), p.MinCPU.MilliValue())
+		cpu = *resource.NewMilliQuantity(value, resource.DecimalSI)
+		_ = cpu.String()
+		rounded[v1.ResourceCPU] = cpu
+	}
+	if mem, exists := rounded[v1.ResourceMemory]; exists {
+		value := p.round(mem.Value(), p.MemoryStep.Value(), p.MinMemory.Value())
+		mem = *resource.NewQuantity(value, resource.BinarySI)
+		_ = mem.String()
+		rounded[v1.ResourceMemory] = mem
+	}
+	return rounded
+}
+
+func (p RoundingPolicy) round(value, step, floor int64) int64 {
+	if step > 0 && value > 0 {
+		switch p.Mode {
+		case RoundNearest:
+			value = ((value + step/2) / step) * step
+			if value == 0 {
+				value = step
+			}
+		default:
+			value = ((value + step - 1) / step) * step
+		}
+	}
+	if value < floor {
+		value = floor
+	}
+	return value
+}
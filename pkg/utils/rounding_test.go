@@ -0,0 +1,64 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestRoundingPolicy(t *testing.T) {
+	tests := []struct {
+		description string
+		cpuStep     string
+		memoryStep  string
+		mode        string
+		minCPU      string
+		minMemory   string
+		cpu         string
+		memory      string
+		wantCPU     string
+		wantMemory  string
+	}{
+		{"no policy", "", "", "", "", "", "23m", "105Mi", "23m", "105Mi"},
+		{"round up", "10m", "16Mi", "up", "", "", "23m", "105Mi", "30m", "112Mi"},
+		{"round nearest", "10m", "64Mi", "nearest", "", "", "23m", "105Mi", "20m", "128Mi"},
+		{"nearest never rounds to zero", "50m", "", "nearest", "", "", "5m", "105Mi", "50m", "105Mi"},
+		{"already on a step", "50m", "64Mi", "up", "", "", "1", "1Gi", "1", "1Gi"},
+		{"floors", "10m", "16Mi", "up", "100m", "256Mi", "23m", "105Mi", "100m", "256Mi"},
+	}
+	for _, tc := range tests {
+		policy, err := NewRoundingPolicy(tc.cpuStep, tc.memoryStep, tc.mode, tc.minCPU, tc.minMemory)
+		assert.NoError(t, err, tc.description)
+
+		input := v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(tc.cpu),
+			v1.ResourceMemory: resource.MustParse(tc.memory),
+		}
+		got := policy.Apply(input)
+		assert.Equal(t, tc.wantCPU, got.Cpu().String(), tc.description)
+		assert.Equal(t, tc.wantMemory, got.Memory().String(), tc.description)
+		assert.Equal(t, tc.cpu, input.Cpu().String(), "input is not modified")
+	}
+
+	_, err := NewRoundingPolicy("10m", "", "sideways", "", "")
+	assert.Error(t, err)
+	_, err = NewRoundingPolicy("ten", "", "", "", "")
+	assert.Error(t, err)
+	assert.Nil(t, RoundingPolicy{CPUStep: resource.MustParse("10m")}.Apply(nil))
+}
@@ -0,0 +1,108 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Tolerance is a band around a recommendation in which a current value is
+// considered equal to the recommendation. The band for a resource is the larger
+// of its percentage (relative to the recommendation) and its absolute floor.
+type Tolerance struct {
+	Percent  map[v1.ResourceName]float64
+	Absolute map[v1.ResourceName]resource.Quantity
+}
+
+// ParseTolerance parses a comma delimited list of resource tolerances, for example
+// "cpu=10%,memory=10%,cpu=5m,memory=16Mi". Percentages and absolute floors may be
+// given for the same resource.
+func ParseTolerance(value string) (Tolerance, error) {
+	tolerance := Tolerance{
+		Percent:  map[v1.ResourceName]float64{},
+		Absolute: map[v1.ResourceName]resource.Quantity{},
+	}
+	if strings.TrimSpace(value) == "" {
+		return tolerance, nil
+	}
+
+	for _, item := range strings.Split(value, ",") {
+		name, amount, found := strings.Cut(strings.TrimSpace(item), "=")
+		if !found || name == "" || amount == "" {
+			return tolerance, fmt.Errorf("invalid tolerance %q, expected <resource>=<percent>%% or <resource>=<quantity>", item)
+		}
+		resourceName := v1.ResourceName(strings.TrimSpace(name))
+		amount = strings.TrimSpace(amount)
+
+		if percent, isPercent := strings.CutSuffix(amount, "%"); isPercent {
+			parsed, err := strconv.ParseFloat(percent, 64)
+			if err != nil || parsed < 0 {
+				return tolerance, fmt.Errorf("invalid tolerance percentage %q for %s", amount, resourceName)
+			}
+			tolerance.Percent[resourceName] = parsed
+			continue
+		}
+
+		quantity, err := resource.ParseQuantity(amount)
+		if err != nil || quantity.Sign() < 0 {
+			return tolerance, fmt.Errorf("invalid tolerance quantity %q for %s", amount, resourceName)
+		}
+		tolerance.Absolute[resourceName] = quantity
+	}
+	return tolerance, nil
+}
+
+// IsZero returns true if the tolerance allows no difference at all
+func (t Tolerance) IsZero() bool {
+	return len(t.Percent) == 0 && len(t.Absolute) == 0
+}
+
+// Within returns true if the existing value is within the tolerance of the recommendation.
+// An unset existing value is never within the tolerance.
+func (t Tolerance) Within(name v1.ResourceName, existing, recommendation resource.Quantity) bool {
+	if existing.IsZero() {
+		return false
+	}
+	if existing.Cmp(recommendation) == 0 {
+		return true
+	}
+
+	// compare in milli-units so that cpu and memory are both whole numbers
+	band := 0.0
+	if percent, ok := t.Percent[name]; ok {
+		band = float64(recommendation.MilliValue()) * percent / 100
+	}
+	if absolute, ok := t.Absolute[name]; ok {
+		band = math.Max(band, float64(absolute.MilliValue()))
+	}
+
+	diff := math.Abs(float64(existing.MilliValue() - recommendation.MilliValue()))
+	return diff <= band
+}
+
+// Compare compares the existing value to the recommendation, treating values within
+// the tolerance as equal. It returns -1, 0 or 1 like resource.Quantity.Cmp.
+func (t Tolerance) Compare(name v1.ResourceName, existing, recommendation resource.Quantity) int {
+	if t.Within(name, existing, recommendation) {
+		return 0
+	}
+	return existing.Cmp(recommendation)
+}
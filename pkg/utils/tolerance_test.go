@@ -0,0 +1,71 @@
+// Copyright 2019 FairwindsOps Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestParseTolerance(t *testing.T) {
+	tolerance, err := ParseTolerance("cpu=10%, memory=5%,cpu=20m,memory=16Mi")
+	assert.NoError(t, err)
+	assert.Equal(t, 10.0, tolerance.Percent[v1.ResourceCPU])
+	assert.Equal(t, 5.0, tolerance.Percent[v1.ResourceMemory])
+	cpu := tolerance.Absolute[v1.ResourceCPU]
+	memory := tolerance.Absolute[v1.ResourceMemory]
+	assert.Equal(t, "20m", cpu.String())
+	assert.Equal(t, "16Mi", memory.String())
+
+	empty, err := ParseTolerance("")
+	assert.NoError(t, err)
+	assert.True(t, empty.IsZero())
+
+	for _, invalid := range []string{"cpu", "cpu=", "cpu=abc%", "cpu=-5%", "memory=lots"} {
+		_, err := ParseTolerance(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestToleranceCompare(t *testing.T) {
+	tolerance, err := ParseTolerance("cpu=10%,memory=16Mi")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		description    string
+		name           v1.ResourceName
+		existing       string
+		recommendation string
+		expected       int
+	}{
+		{"cpu exactly equal", v1.ResourceCPU, "100m", "100m", 0},
+		{"cpu within percentage", v1.ResourceCPU, "105m", "100m", 0},
+		{"cpu on the edge of percentage", v1.ResourceCPU, "90m", "100m", 0},
+		{"cpu below percentage", v1.ResourceCPU, "80m", "100m", -1},
+		{"cpu above percentage", v1.ResourceCPU, "120m", "100m", 1},
+		{"memory within absolute floor", v1.ResourceMemory, "110Mi", "100Mi", 0},
+		{"memory outside absolute floor", v1.ResourceMemory, "120Mi", "100Mi", 1},
+		{"no tolerance for resource", v1.ResourceEphemeralStorage, "101Mi", "100Mi", 1},
+	}
+	for _, tc := range tests {
+		got := tolerance.Compare(tc.name, resource.MustParse(tc.existing), resource.MustParse(tc.recommendation))
+		assert.Equal(t, tc.expected, got, tc.description)
+	}
+
+	assert.False(t, tolerance.Within(v1.ResourceCPU, resource.Quantity{}, resource.MustParse("1m")), "unset is never within tolerance")
+}
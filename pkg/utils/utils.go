@@ -32,6 +32,10 @@ var (
 	WorkloadExcludeContainersAnnotation = LabelOrAnnotationBase + "/" + "exclude-containers"
 	// VpaResourcePolicyAnnotation is the annotation use to define the json configuration of PodResourcePolicy section of a vpa
 	VpaResourcePolicyAnnotation = LabelOrAnnotationBase + "/" + "vpa-resource-policy"
+	// RecommendationHeadroomAnnotation is the annotation used to add a safety margin on top of the recommended target, e.g. cpu=20%,memory=10%
+	RecommendationHeadroomAnnotation = LabelOrAnnotationBase + "/" + "recommendation-headroom"
+	// LimitStrategyAnnotation is the annotation used on a namespace to choose how recommended requests and limits are derived, e.g. no-cpu-limit,ratio:1.5
+	LimitStrategyAnnotation = LabelOrAnnotationBase + "/" + "limit-strategy"
 )
 
 // VPALabels is a set of default labels that get placed on every VPA.